@@ -8,7 +8,6 @@ import (
 	"crypto/sha1"
 	"errors"
 	"fmt"
-	"hash"
 	"regexp"
 	"strings"
 	"sync"
@@ -90,11 +89,9 @@ func (as *attributeStorage) GetAttribute(k string) *Attribute {
 }
 
 var attStorage attributeStorage
-var h hash.Hash
 
 func init() {
 	attStorage.Items = make(map[string]*Attribute)
-	h = sha1.New()
 }
 
 // 从字符串中解析出属性
@@ -108,9 +105,8 @@ func Parse(attStr string) (*Attribute, error) {
 	}
 	var result Attribute
 
-	h.Reset()
-	h.Write([]byte(attStr))
-	khash := fmt.Sprintf("%x", h.Sum(nil))
+	// 多个引擎可能并发解析，这里不共用哈希对象
+	khash := fmt.Sprintf("%x", sha1.Sum([]byte(attStr)))
 
 	v := attStorage.GetAttribute(khash)
 	if v != nil {
@@ -165,7 +161,6 @@ func Parse(attStr string) (*Attribute, error) {
 				switch r {
 				case rune(' '):
 					continue
-					break
 				case rune('\''):
 					gkTag = rune('\'')
 					gkStart = 1
@@ -7,7 +7,6 @@ package attribute
 import (
 	"errors"
 	// "fmt"
-	"strconv"
 	"strings"
 )
 
@@ -52,21 +51,13 @@ func FuncParser(funcStr string) (string, []interface{}, error) {
 
 				currentStr := strings.TrimSpace(string(currentArg))
 				currentArg = []rune(currentStr)
-
-				if strings.ToLower(currentStr) == "true" || strings.ToLower(currentStr) == "false" {
-					if s, err := strconv.ParseBool(strings.ToLower(currentStr)); err == nil {
-						thisArg = s
-					}
-				}
-				if s, err := strconv.ParseFloat(currentStr, 64); err == nil {
-					thisArg = s
+				if len(currentArg) == 0 {
+					continue
 				}
-				if s, err := strconv.ParseInt(currentStr, 10, 64); err == nil {
-					thisArg = s
-				}
-				if (currentArg[0] == rune('"') && currentArg[len(currentArg)-1] == rune('"')) ||
+
+				if len(currentArg) > 1 && ((currentArg[0] == rune('"') && currentArg[len(currentArg)-1] == rune('"')) ||
 					(currentArg[0] == rune('\'') && currentArg[len(currentArg)-1] == rune('\'')) ||
-					(currentArg[0] == rune('`') && currentArg[len(currentArg)-1] == rune('`')) {
+					(currentArg[0] == rune('`') && currentArg[len(currentArg)-1] == rune('`'))) {
 					thisArg = string(currentArg[1 : len(currentArg)-1])
 				} else {
 					thisArg = currentStr
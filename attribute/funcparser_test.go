@@ -23,5 +23,11 @@ func TestFuncParser(t *testing.T) {
 	if len(args) != 8 {
 		t.Errorf("TestFuncParser not passed,args is wrong")
 	}
+	expect := []interface{}{"@me", "1", "2", "测试数据", "true", "1.321", "okkkk", "哦1121😯"}
+	for i, arg := range expect {
+		if i < len(args) && args[i] != arg {
+			t.Errorf("TestFuncParser arg %d=%#v, expect %#v", i, args[i], arg)
+		}
+	}
 
 }
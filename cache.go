@@ -0,0 +1,162 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板片段缓存
+package gktemplate
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认片段缓存的大小上限
+const DefaultCacheSize = 32 << 20
+
+// FragmentCache 片段缓存，cache标签使用，可以替换为Redis等外部缓存
+type FragmentCache interface {
+	Get(key string) (string, bool)            // 获取缓存，不存在或已过期返回false
+	Set(key, value string, ttl time.Duration) // 设置缓存，ttl为0表示不过期
+	Delete(key string)                        // 删除缓存
+	DeletePrefix(prefix string)               // 删除指定前缀的缓存
+}
+
+// MemoryCache 内存片段缓存，超过大小上限时淘汰最久未使用的内容
+type MemoryCache struct {
+	maxSize int                      // 键和内容的总字节数上限
+	size    int                      // 当前总字节数
+	items   map[string]*list.Element // 缓存项
+	lru     *list.List               // 最近使用的在前
+	sync.Mutex
+}
+
+// 缓存项
+type cacheItem struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+// 创建内存片段缓存，maxSize为键和内容的总字节数上限，0表示不限制
+func NewMemoryCache(maxSize int) *MemoryCache {
+	return &MemoryCache{
+		maxSize: maxSize,
+		items:   make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (mc *MemoryCache) Get(key string) (string, bool) {
+	mc.Lock()
+	defer mc.Unlock()
+	el, ok := mc.items[key]
+	if !ok {
+		return "", false
+	}
+	item := el.Value.(*cacheItem)
+	if !item.expires.IsZero() && time.Now().After(item.expires) {
+		mc.remove(el)
+		return "", false
+	}
+	mc.lru.MoveToFront(el)
+	return item.value, true
+}
+
+func (mc *MemoryCache) Set(key, value string, ttl time.Duration) {
+	mc.Lock()
+	defer mc.Unlock()
+	if el, ok := mc.items[key]; ok {
+		mc.remove(el)
+	}
+	n := len(key) + len(value)
+	if mc.maxSize > 0 && n > mc.maxSize {
+		return
+	}
+	item := &cacheItem{key: key, value: value}
+	if ttl > 0 {
+		item.expires = time.Now().Add(ttl)
+	}
+	mc.items[key] = mc.lru.PushFront(item)
+	mc.size += n
+	for mc.maxSize > 0 && mc.size > mc.maxSize {
+		mc.remove(mc.lru.Back())
+	}
+}
+
+func (mc *MemoryCache) Delete(key string) {
+	mc.Lock()
+	defer mc.Unlock()
+	if el, ok := mc.items[key]; ok {
+		mc.remove(el)
+	}
+}
+
+func (mc *MemoryCache) DeletePrefix(prefix string) {
+	mc.Lock()
+	defer mc.Unlock()
+	for key, el := range mc.items {
+		if strings.HasPrefix(key, prefix) {
+			mc.remove(el)
+		}
+	}
+}
+
+// 缓存项数量
+func (mc *MemoryCache) Len() int {
+	mc.Lock()
+	defer mc.Unlock()
+	return len(mc.items)
+}
+
+// 当前键和内容的总字节数
+func (mc *MemoryCache) Size() int {
+	mc.Lock()
+	defer mc.Unlock()
+	return mc.size
+}
+
+func (mc *MemoryCache) remove(el *list.Element) {
+	item := mc.lru.Remove(el).(*cacheItem)
+	delete(mc.items, item.key)
+	mc.size -= len(item.key) + len(item.value)
+}
+
+// 设置片段缓存，nil表示不缓存，cache标签每次都渲染内容
+func (e *Engine) SetFragmentCache(c FragmentCache) {
+	e.Lock()
+	defer e.Unlock()
+	e.cache = c
+}
+
+// 获取片段缓存
+func (e *Engine) FragmentCache() FragmentCache {
+	e.RLock()
+	defer e.RUnlock()
+	return e.cache
+}
+
+// 删除片段缓存，包括各个语言的缓存，例：InvalidateFragment("nav")
+func (e *Engine) InvalidateFragment(key string) {
+	if c := e.FragmentCache(); c != nil {
+		c.Delete(key)
+		c.DeletePrefix(key + "|")
+	}
+}
+
+// 删除指定前缀的片段缓存，例：InvalidateFragmentPrefix("hot:")
+func (e *Engine) InvalidateFragmentPrefix(prefix string) {
+	if c := e.FragmentCache(); c != nil {
+		c.DeletePrefix(prefix)
+	}
+}
+
+// 删除默认引擎的片段缓存
+func InvalidateFragment(key string) {
+	defaultEngine.InvalidateFragment(key)
+}
+
+// 删除默认引擎指定前缀的片段缓存
+func InvalidateFragmentPrefix(prefix string) {
+	defaultEngine.InvalidateFragmentPrefix(prefix)
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 片段缓存单元测试
+package gktemplate
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	mc := NewMemoryCache(10)
+	mc.Set("a", "1234", 0)
+	mc.Set("b", "1234", 0)
+	if _, ok := mc.Get("a"); !ok {
+		t.Error("cache a should exist")
+	}
+	// 超过大小上限时淘汰最久未使用的b
+	mc.Set("c", "12", 0)
+	if _, ok := mc.Get("b"); ok || mc.Len() != 2 || mc.Size() != 8 {
+		t.Errorf("cache len=%d size=%d after eviction", mc.Len(), mc.Size())
+	}
+	mc.Set("big", "12345678901", 0)
+	if _, ok := mc.Get("big"); ok {
+		t.Error("value larger than max size should not be cached")
+	}
+
+	mc.Set("ttl", "1", time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := mc.Get("ttl"); ok {
+		t.Error("expired cache should not exist")
+	}
+
+	mc = NewMemoryCache(0)
+	mc.Set("hot:1", "a", 0)
+	mc.Set("hot:2", "b", 0)
+	mc.Set("nav", "c", 0)
+	mc.DeletePrefix("hot:")
+	mc.Delete("none")
+	if mc.Len() != 1 {
+		t.Errorf("cache len=%d after DeletePrefix", mc.Len())
+	}
+}
+
+func TestTagCache(t *testing.T) {
+	e := New()
+	n := 0
+	e.RegisterLib("counter", func(tag *GKTag, data *D) string {
+		n++
+		return strconv.Itoa(n)
+	})
+	tpl := `<{gk:cache key="hot:{typeid}" ttl="1m"}><{gk:counter/}>:[field:typeid/]<{/gk:cache}>`
+	render := func(typeid int) string {
+		rs, err := e.ParseString(tpl, D{"typeid": typeid})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rs
+	}
+	if rs := render(1) + render(1) + render(2); rs != "1:11:12:2" {
+		t.Errorf("cache result=%q", rs)
+	}
+
+	e.InvalidateFragment("hot:1")
+	if rs := render(1) + render(2); rs != "3:12:2" {
+		t.Errorf("invalidate result=%q", rs)
+	}
+	e.InvalidateFragmentPrefix("hot:")
+	if rs := render(1) + render(2); rs != "4:15:2" {
+		t.Errorf("invalidate prefix result=%q", rs)
+	}
+
+	e.SetFragmentCache(nil)
+	if rs := render(1) + render(1); rs != "6:17:1" {
+		t.Errorf("no cache result=%q", rs)
+	}
+
+	_, err := e.ParseString(`<{gk:cache key="a" ttl="1 day"}>a<{/gk:cache}>`, nil)
+	if re, ok := err.(*RenderError); !ok || re.Tag != "gk:cache" {
+		t.Errorf("invalid ttl error=%v", err)
+	}
+}
+
+// 不同语言的渲染结果分别缓存
+func TestTagCacheLocale(t *testing.T) {
+	e := New()
+	e.AddMessages("en", Messages{"home": {PluralOther: "Home"}})
+	e.AddMessages("zh", Messages{"home": {PluralOther: "首页"}})
+	tpl := `<{gk:cache key="nav"}><{gk:t key="home"/}><{/gk:cache}>`
+	render := func(data D) string {
+		rs, err := e.ParseString(tpl, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rs
+	}
+	if rs := render(D{"locale": "en"}) + "," + render(D{"locale": "zh"}) + "," + render(D{"locale": "en"}); rs != "Home,首页,Home" {
+		t.Errorf("cache locale result=%q", rs)
+	}
+	e.SetDefaultLocale("zh")
+	if rs := render(nil); rs != "首页" {
+		t.Errorf("cache default locale result=%q", rs)
+	}
+	if _, ok := e.FragmentCache().Get("nav|en"); !ok {
+		t.Errorf("cache key should contain the locale")
+	}
+	e.InvalidateFragment("nav")
+	if _, ok := e.FragmentCache().Get("nav|en"); ok {
+		t.Errorf("InvalidateFragment should delete every locale")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// Package gkchi chi路由使用的模板引擎中间件
+//
+// 中间件把模板引擎放入请求的Context，处理函数使用Render渲染模板，
+// chi的路由参数放在模板数据的params变量中：
+//
+//	r := chi.NewRouter()
+//	r.Use(gkchi.Middleware(engine))
+//	r.Get("/news/{id}", func(w http.ResponseWriter, r *http.Request) {
+//		// 模板中使用<{gk:field name="params.id"/}>
+//		gkchi.Render(w, r, "news.htm", gktemplate.D{"info": "GoKeep"})
+//	})
+package gkchi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	gkt "github.com/gokeeptech/gktemplate"
+	gkhttp "github.com/gokeeptech/gktemplate/http"
+)
+
+// 路由参数在模板数据中的变量名称
+const ParamsKey = "params"
+
+type contextKey struct{}
+
+// 创建中间件，把模板引擎及其HTTP渲染器放入请求的Context，e为nil时使用默认引擎
+func Middleware(e *gkt.Engine) func(http.Handler) http.Handler {
+	return MiddlewareRenderer(gkhttp.New(e))
+}
+
+// 使用指定的HTTP渲染器创建中间件，可以预先设置错误页面模板、整页缓存
+func MiddlewareRenderer(rd *gkhttp.Renderer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithRenderer(r.Context(), rd)))
+		})
+	}
+}
+
+// 返回包含HTTP渲染器的Context
+func WithRenderer(ctx context.Context, rd *gkhttp.Renderer) context.Context {
+	return context.WithValue(ctx, contextKey{}, rd)
+}
+
+// 获取Context中的HTTP渲染器，不存在则返回默认引擎的渲染器
+func RendererFrom(ctx context.Context) *gkhttp.Renderer {
+	if rd, ok := ctx.Value(contextKey{}).(*gkhttp.Renderer); ok {
+		return rd
+	}
+	return gkhttp.Default()
+}
+
+// 获取Context中的模板引擎，不存在则返回默认引擎
+func EngineFrom(ctx context.Context) *gkt.Engine {
+	return RendererFrom(ctx).Engine()
+}
+
+// 使用请求Context中的模板引擎渲染模板并写入响应，出错时写入错误页面，参见gkhttp.Renderer.Render
+// chi的路由参数以gkt.D放在params变量中，data中已有params时不覆盖
+func Render(w http.ResponseWriter, r *http.Request, name string, data gkt.D) error {
+	return RendererFrom(r.Context()).Render(w, r, name, withParams(r, data))
+}
+
+// 复制数据并加入路由参数，不修改调用者的数据
+func withParams(r *http.Request, data gkt.D) gkt.D {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || len(rctx.URLParams.Keys) == 0 {
+		return data
+	}
+	if _, ok := data[ParamsKey]; ok {
+		return data
+	}
+	params := make(gkt.D, len(rctx.URLParams.Keys))
+	for i, k := range rctx.URLParams.Keys {
+		params[k] = rctx.URLParams.Values[i]
+	}
+	d := make(gkt.D, len(data)+1)
+	for k, v := range data {
+		d[k] = v
+	}
+	d[ParamsKey] = params
+	return d
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// chi中间件单元测试
+package gkchi
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	gkt "github.com/gokeeptech/gktemplate"
+	gkhttp "github.com/gokeeptech/gktemplate/http"
+)
+
+func TestMiddleware(t *testing.T) {
+	files := map[string]string{
+		"simple.htm": `<h1><{gk:field name="info"/}> <{gk:field name="params.id"/}></h1>`,
+	}
+	engine := gkt.New()
+	engine.SetLoader(gkt.LoaderFunc(func(name string) (string, error) {
+		s, ok := files[name]
+		if !ok {
+			return "", os.ErrNotExist
+		}
+		return s, nil
+	}))
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	r := chi.NewRouter()
+	r.Use(Middleware(engine))
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if EngineFrom(r.Context()) != engine {
+			t.Error("engine is not injected")
+		}
+		Render(w, r, "simple.htm", gkt.D{"info": "GoKeep"})
+	})
+	r.Get("/missing/", func(w http.ResponseWriter, r *http.Request) {
+		Render(w, r, "none.htm", nil)
+	})
+
+	for _, c := range []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/1", http.StatusOK, "<h1>GoKeep 1</h1>"},
+		{"/missing/", http.StatusNotFound, "Not Found\n"},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+		if w.Code != c.code || w.Body.String() != c.body {
+			t.Errorf("%s code=%d body=%q, want %d %q", c.path, w.Code, w.Body.String(), c.code, c.body)
+		}
+		if c.code == http.StatusOK && w.Header().Get("Content-Type") != gkhttp.ContentTypeHTML {
+			t.Errorf("%s content type=%q", c.path, w.Header().Get("Content-Type"))
+		}
+	}
+
+	// 没有使用中间件时使用默认引擎
+	if EngineFrom(context.Background()) != gkt.Default() {
+		t.Error("expected default engine")
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/gokeeptech/gktemplate/chi
+
+go 1.22
+
+require (
+	github.com/go-chi/chi/v5 v5.2.5
+	github.com/gokeeptech/gktemplate v0.1.0
+)
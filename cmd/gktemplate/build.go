@@ -0,0 +1,94 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	gkt "github.com/gokeeptech/gktemplate"
+	"github.com/gokeeptech/gktemplate/site"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	buildForce  *bool
+	buildOutput *string
+)
+
+// gktemplate build [-force] [-o dir] site.json
+var cmdBuild = &command{
+	Name: "build",
+	Init: func(fs *flag.FlagSet) {
+		buildForce = fs.Bool("force", false, "rewrite files even if unchanged")
+		buildOutput = fs.String("o", "", "output directory, overrides the site description")
+	},
+	Run: func(e *gkt.Engine, fs *flag.FlagSet, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		if len(args) != 1 {
+			fmt.Fprintln(stderr, "usage: gktemplate build [-force] [-o dir] site.json|site.yaml")
+			return 2
+		}
+		s, err := loadSite(args[0])
+		if err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+		if *buildOutput != "" {
+			s.Output = *buildOutput
+		}
+
+		g := &site.Generator{
+			Engine: e,
+			Decoders: map[string]site.Decoder{
+				"yaml": decodeYAML,
+				"yml":  decodeYAML,
+			},
+			Force: *buildForce,
+		}
+		t := time.Now()
+		results, err := g.Build(s)
+
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		changed := 0
+		for _, rs := range results {
+			state := "unchanged"
+			if rs.Changed {
+				state = "written"
+				changed++
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", state, rs.Duration, filepath.ToSlash(rs.Output))
+		}
+		tw.Flush()
+		if err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%d pages, %d written in %s\n", len(results), changed, time.Since(t))
+		return 0
+	},
+}
+
+// 载入站点描述，根据扩展名判断JSON/YAML格式
+func loadSite(filename string) (*site.Site, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".yaml" && ext != ".yml" {
+		return site.Load(filename)
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var s site.Site
+	if err := yaml.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	s.Resolve(filepath.Dir(filename))
+	return &s, nil
+}
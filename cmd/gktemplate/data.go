@@ -0,0 +1,73 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	gkt "github.com/gokeeptech/gktemplate"
+	"github.com/gokeeptech/gktemplate/site"
+	"gopkg.in/yaml.v3"
+)
+
+// 读取数据文件，"-"表示从标准输入读取
+// 根据扩展名判断格式，标准输入使用format指定格式
+func loadData(filename, format string, stdin io.Reader) (gkt.D, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if filename == "" {
+		return gkt.D{}, nil
+	}
+	if filename == "-" {
+		b, err = ioutil.ReadAll(stdin)
+	} else {
+		b, err = ioutil.ReadFile(filename)
+		if format == "" {
+			format = strings.TrimPrefix(filepath.Ext(filename), ".")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeData(b, format)
+}
+
+// 解析JSON/YAML数据
+func decodeData(b []byte, format string) (gkt.D, error) {
+	var v interface{}
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+	case "", "json":
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported data format %q", format)
+	}
+	if v == nil {
+		return gkt.D{}, nil
+	}
+	d, ok := site.Normalize(v).(gkt.D)
+	if !ok {
+		return nil, fmt.Errorf("data must be an object")
+	}
+	return d, nil
+}
+
+// 解析YAML数据，用于站点生成器
+func decodeYAML(b []byte) (interface{}, error) {
+	var v interface{}
+	err := yaml.Unmarshal(b, &v)
+	return v, err
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+var (
+	dedeOutput *string
+	dedeBase   *string
+	dedeReport *string
+)
+
+// gktemplate dede -o dir [-base dir] [-report file] pattern...
+var cmdDede = &command{
+	Name: "dede",
+	Init: func(fs *flag.FlagSet) {
+		dedeOutput = fs.String("o", "", "output directory for converted templates")
+		dedeBase = fs.String("base", ".", "source root, output paths are relative to it")
+		dedeReport = fs.String("report", "", "also write the conversion report to this file")
+	},
+	Run: func(e *gkt.Engine, fs *flag.FlagSet, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		if len(args) == 0 || *dedeOutput == "" {
+			fmt.Fprintln(stderr, "usage: gktemplate dede -o dir [-base dir] [-report file] pattern...")
+			return 2
+		}
+		files, err := expand(args)
+		if err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+
+		report := stdout
+		if *dedeReport != "" {
+			f, err := os.Create(*dedeReport)
+			if err != nil {
+				fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+				return 1
+			}
+			defer f.Close()
+			report = io.MultiWriter(stdout, f)
+		}
+
+		errs, warns := 0, 0
+		for _, f := range files {
+			dst := filepath.Join(*dedeOutput, outputName(*dedeBase, f))
+			diags, err := e.ConvertDedeFile(f, dst)
+			if err != nil {
+				fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+				return 1
+			}
+			fmt.Fprintf(report, "converted %s -> %s\n", f, dst)
+			for _, d := range diags {
+				fmt.Fprintln(report, d)
+				if d.Severity == gkt.SeverityError {
+					errs++
+				} else {
+					warns++
+				}
+			}
+		}
+		fmt.Fprintf(report, "%d files, %d errors, %d warnings\n", len(files), errs, warns)
+		if errs > 0 {
+			return 1
+		}
+		return 0
+	},
+}
+
+// 输出文件相对于源目录的路径，不在源目录中则只保留文件名
+func outputName(base, f string) string {
+	rel, err := filepath.Rel(base, f)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Base(f)
+	}
+	return rel
+}
@@ -0,0 +1,178 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+var (
+	extractOutput *string
+	extractFormat *string
+	extractLocale *string
+	extractMerge  *string
+)
+
+// 提取的消息
+type extractedMessage struct {
+	key    string
+	plural bool
+	refs   []string // 使用位置，例：index.htm:3
+}
+
+// gktemplate extract [-o file] [-format json|po] [-locale name] [-merge catalog] pattern...
+var cmdExtract = &command{
+	Name: "extract",
+	Init: func(fs *flag.FlagSet) {
+		extractOutput = fs.String("o", "", "output catalog file, default stdout")
+		extractFormat = fs.String("format", "", "catalog format json or po, default from output extension or json")
+		extractLocale = fs.String("locale", "en", "locale deciding plural categories")
+		extractMerge = fs.String("merge", "", "existing catalog whose translations are kept")
+	},
+	Run: func(e *gkt.Engine, fs *flag.FlagSet, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		if len(args) == 0 {
+			fmt.Fprintln(stderr, "usage: gktemplate extract [-o file] [-format json|po] [-locale name] [-merge catalog] pattern...")
+			return 2
+		}
+		format := *extractFormat
+		if format == "" {
+			format = "json"
+			if strings.ToLower(filepath.Ext(*extractOutput)) == ".po" {
+				format = "po"
+			}
+		}
+		if format != "json" && format != "po" {
+			fmt.Fprintf(stderr, "gktemplate: unknown format %q\n", format)
+			return 2
+		}
+		files, err := expand(args)
+		if err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+
+		var msgs []*extractedMessage
+		seen := make(map[string]*extractedMessage)
+		for _, f := range files {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+				return 1
+			}
+			refs, diags := e.ExtractMessages(filepath.ToSlash(f), string(b))
+			for _, d := range diags {
+				if d.Severity == gkt.SeverityError {
+					fmt.Fprintln(stderr, d)
+				}
+			}
+			for _, r := range refs {
+				m := seen[r.Key]
+				if m == nil {
+					m = &extractedMessage{key: r.Key}
+					seen[r.Key] = m
+					msgs = append(msgs, m)
+				}
+				m.plural = m.plural || r.Plural
+				m.refs = append(m.refs, fmt.Sprintf("%s:%d", r.Name, r.Line))
+			}
+		}
+
+		old := gkt.Messages{}
+		if *extractMerge != "" {
+			b, err := ioutil.ReadFile(*extractMerge)
+			if err != nil {
+				fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+				return 1
+			}
+			if strings.ToLower(filepath.Ext(*extractMerge)) == ".po" {
+				old, err = gkt.ParsePO(*extractLocale, string(b))
+			} else {
+				old, err = gkt.ParseJSONCatalog(string(b))
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "gktemplate: %s: %s\n", *extractMerge, err)
+				return 1
+			}
+		}
+
+		var out []byte
+		if format == "po" {
+			out = writePO(msgs, old, *extractLocale)
+		} else {
+			out = writeJSONCatalog(msgs, old, gkt.PluralCategories(*extractLocale))
+		}
+
+		if *extractOutput == "" {
+			stdout.Write(out)
+			return 0
+		}
+		if err := os.MkdirAll(filepath.Dir(*extractOutput), 0755); err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+		if err := ioutil.WriteFile(*extractOutput, out, 0644); err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "extracted %s: %d messages from %d files\n", *extractOutput, len(msgs), len(files))
+		return 0
+	},
+}
+
+// 生成JSON消息目录，复数消息为复数类别到译文的对象，保留已有的译文
+func writeJSONCatalog(msgs []*extractedMessage, old gkt.Messages, categories []string) []byte {
+	catalog := make(map[string]interface{}, len(msgs))
+	for _, m := range msgs {
+		if !m.plural {
+			catalog[m.key] = old[m.key][gkt.PluralOther]
+			continue
+		}
+		forms := make(map[string]string, len(categories))
+		for _, c := range categories {
+			forms[c] = old[m.key][c]
+		}
+		catalog[m.key] = forms
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(catalog)
+	return buf.Bytes()
+}
+
+// 生成PO消息目录，按消息在模板中出现的顺序排列，保留已有的译文
+// 头部包含语言的Plural-Forms，复数消息的msgstr[n]按Plural-Forms排列
+func writePO(msgs []*extractedMessage, old gkt.Messages, locale string) []byte {
+	forms, categories := gkt.PluralForms(locale)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "msgid \"\"\nmsgstr \"\"\n%s\n%s\n",
+		strconv.Quote("Content-Type: text/plain; charset=UTF-8\n"), strconv.Quote("Language: "+locale+"\n"))
+	if forms != "" {
+		fmt.Fprintf(&buf, "%s\n", strconv.Quote("Plural-Forms: "+forms+"\n"))
+	}
+	for _, m := range msgs {
+		fmt.Fprintf(&buf, "\n#: %s\nmsgid %s\n", strings.Join(m.refs, " "), strconv.Quote(m.key))
+		if !m.plural {
+			fmt.Fprintf(&buf, "msgstr %s\n", strconv.Quote(old[m.key][gkt.PluralOther]))
+			continue
+		}
+		fmt.Fprintf(&buf, "msgid_plural %s\n", strconv.Quote(m.key))
+		for i, c := range categories {
+			fmt.Fprintf(&buf, "msgstr[%d] %s\n", i, strconv.Quote(old[m.key][c]))
+		}
+	}
+	return buf.Bytes()
+}
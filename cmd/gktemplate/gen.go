@@ -0,0 +1,79 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+var (
+	genOutput *string
+	genPkg    *string
+	genRoot   *string
+)
+
+// gktemplate gen -o file [-pkg name] [-root dir] pattern...
+var cmdGen = &command{
+	Name: "gen",
+	Init: func(fs *flag.FlagSet) {
+		genOutput = fs.String("o", "", "output Go file")
+		genPkg = fs.String("pkg", "", "package name, default output directory name")
+		genRoot = fs.String("root", "", "template root, patterns and template names are relative to it")
+	},
+	Run: func(e *gkt.Engine, fs *flag.FlagSet, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		if len(args) == 0 || *genOutput == "" {
+			fmt.Fprintln(stderr, "usage: gktemplate gen -o file [-pkg name] [-root dir] pattern...")
+			return 2
+		}
+		patterns := args
+		if *genRoot != "" {
+			e.SetLoader(gkt.FileLoader{Root: *genRoot})
+			patterns = make([]string, len(args))
+			for i, p := range args {
+				patterns[i] = filepath.Join(*genRoot, p)
+			}
+		}
+		files, err := expand(patterns)
+		if err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+		names := make([]string, len(files))
+		for i, f := range files {
+			names[i] = filepath.ToSlash(f)
+			if *genRoot != "" {
+				rel, _ := filepath.Rel(*genRoot, f)
+				names[i] = filepath.ToSlash(rel)
+			}
+		}
+
+		pkg := *genPkg
+		if pkg == "" {
+			abs, _ := filepath.Abs(filepath.Dir(*genOutput))
+			pkg = filepath.Base(abs)
+		}
+		code, err := e.GenerateGo(pkg, names)
+		if err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+		if err := os.MkdirAll(filepath.Dir(*genOutput), 0755); err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+		if err := ioutil.WriteFile(*genOutput, code, 0644); err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "generated %s: %d templates\n", *genOutput, len(names))
+		return 0
+	},
+}
@@ -0,0 +1,8 @@
+module github.com/gokeeptech/gktemplate/cmd/gktemplate
+
+go 1.12
+
+require (
+	github.com/gokeeptech/gktemplate v0.1.0
+	gopkg.in/yaml.v3 v3.0.1
+)
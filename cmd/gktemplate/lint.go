@@ -0,0 +1,52 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+var lintStrict *bool
+
+// gktemplate lint [-strict] pattern...
+var cmdLint = &command{
+	Name: "lint",
+	Init: func(fs *flag.FlagSet) {
+		lintStrict = fs.Bool("strict", false, "treat warnings as errors")
+	},
+	Run: func(e *gkt.Engine, fs *flag.FlagSet, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		if len(args) == 0 {
+			fmt.Fprintln(stderr, "usage: gktemplate lint [-strict] pattern...")
+			return 2
+		}
+		files, err := expand(args)
+		if err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+
+		failed := false
+		for _, f := range files {
+			diags, err := e.ValidateFile(f)
+			if err != nil {
+				fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+				return 1
+			}
+			for _, d := range diags {
+				fmt.Fprintln(stdout, d)
+				if d.Severity == gkt.SeverityError || *lintStrict {
+					failed = true
+				}
+			}
+		}
+		if failed {
+			return 1
+		}
+		return 0
+	},
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// gktemplate命令行工具
+//
+// 用法：
+//
+//	gktemplate render [-data file] template
+//	gktemplate lint pattern...
+//	gktemplate tags pattern...
+//	gktemplate build site.json
+//	gktemplate dede -o dir pattern...
+//	gktemplate gen -o file pattern...
+//	gktemplate extract [-o file] pattern...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+const usage = `gktemplate - GKTemplate command line tool
+
+Usage:
+
+	gktemplate <command> [flags] [arguments]
+
+Commands:
+
+	render   render a template with JSON/YAML data to stdout
+	lint     check templates and report positioned errors
+	tags     list tags, attributes and functions used per file
+	build    generate a static site from a JSON/YAML site description
+	dede     convert DedeCMS templates to gktemplate syntax
+	gen      compile templates to a Go package
+	extract  extract translation keys of t tags to a JSON/PO catalog
+
+Run 'gktemplate <command> -h' for command flags.
+`
+
+// 子命令
+type command struct {
+	Name string
+	Run  func(e *gkt.Engine, fs *flag.FlagSet, args []string, stdin io.Reader, stdout, stderr io.Writer) int
+	Init func(fs *flag.FlagSet) // 注册子命令参数
+}
+
+var commands = []*command{
+	cmdRender,
+	cmdLint,
+	cmdTags,
+	cmdBuild,
+	cmdDede,
+	cmdGen,
+	cmdExtract,
+}
+
+func main() {
+	// 引擎加载文件时的日志对命令行输出没有意义
+	log.SetOutput(ioutil.Discard)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// 执行命令，返回退出码
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	for _, cmd := range commands {
+		if cmd.Name != args[0] {
+			continue
+		}
+		fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		ns := fs.String("ns", "", "tag namespace, default gk")
+		start := fs.String("start", "", "tag start delimiter, default <{")
+		end := fs.String("end", "", "tag end delimiter, default }>")
+		trim := fs.Bool("trim", false, "remove indentation and newline of lines holding only a block tag or comment")
+		dede := fs.Bool("dede", false, "DedeCMS compatibility mode (field.title, function attribute, Dede functions)")
+		if cmd.Init != nil {
+			cmd.Init(fs)
+		}
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+
+		e := gkt.New()
+		dns, dstart, dend := e.NameSpace()
+		if *ns != "" || *start != "" || *end != "" {
+			e.SetNameSpace(or(*ns, dns), or(*start, dstart), or(*end, dend))
+		}
+		e.SetTrimBlocks(*trim)
+		if *dede {
+			if err := e.SetDedeCompat(true); err != nil {
+				fmt.Fprintln(stderr, "gktemplate:", err)
+				return 1
+			}
+		}
+		return cmd.Run(e, fs, fs.Args(), stdin, stdout, stderr)
+	}
+	fmt.Fprintf(stderr, "gktemplate: unknown command %q\n\n%s", args[0], usage)
+	return 2
+}
+
+func or(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// 展开文件匹配模式
+func expand(patterns []string) ([]string, error) {
+	var files []string
+	for _, p := range patterns {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", p)
+		}
+		for _, f := range matches {
+			if fi, err := os.Stat(f); err == nil && !fi.IsDir() {
+				files = append(files, f)
+			}
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,251 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+func TestRender(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("info: GoKeep\nitems:\n  - id: 1\n    name: a\n  - id: 2\n    name: b\n")
+	code := run([]string{"render", "-data", "-", "-format", "yaml", "testdata/page.htm"}, stdin, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("render exit %d: %s", code, stderr.String())
+	}
+	expect := "Hello GoKeep\n<li>1-a</li><li>2-b</li>\n"
+	if stdout.String() != expect {
+		t.Errorf("render result=%q", stdout.String())
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"render", "-data", "testdata/page.json", "testdata/page.htm"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("render exit %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "Hello GoKeep") {
+		t.Errorf("render result=%q", stdout.String())
+	}
+}
+
+func TestLint(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"lint", "testdata/page.htm"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Errorf("lint page.htm exit %d: %s", code, stdout.String())
+	}
+
+	stdout.Reset()
+	code = run([]string{"lint", "testdata/*.htm"}, nil, &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("lint should fail on bad.htm")
+	}
+	if !strings.Contains(stdout.String(), "testdata/bad.htm:2:1: error:") {
+		t.Errorf("lint output=%q", stdout.String())
+	}
+}
+
+func TestTagsNameSpace(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"tags", "-ns", "dede", "-start", "{", "-end", "}", "testdata/dede.htm"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("tags exit %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "dede:field") {
+		t.Errorf("tags output=%q", stdout.String())
+	}
+}
+
+// 模板有错误时输出诊断信息并返回非零
+func TestTagsError(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"tags", "testdata/broken.htm"}, nil, &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("tags should fail on broken.htm, exit %d", code)
+	}
+	if !strings.Contains(stderr.String(), "testdata/broken.htm:2:1: error:") {
+		t.Errorf("tags stderr=%q", stderr.String())
+	}
+}
+
+func TestRenderDede(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader(`{"title": "gokeep"}`)
+	code := run([]string{"render", "-ns", "dede", "-start", "{", "-end", "}", "-dede", "-data", "-", "testdata/dede_compat.htm"}, stdin, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("render exit %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "<title>GOKEEP</title>\n" {
+		t.Errorf("render output=%q", stdout.String())
+	}
+}
+
+func TestDede(t *testing.T) {
+	out, err := ioutil.TempDir("", "gkdede")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	var stdout, stderr bytes.Buffer
+	report := filepath.Join(out, "report.txt")
+	code := run([]string{"dede", "-o", out, "-base", "testdata", "-report", report, "testdata/dede/*.htm"}, nil, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("dede exit %d, expect 1: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "testdata/dede/index.htm:2:1: error: tag 'dede:arclist' attribute 'sql'") {
+		t.Errorf("dede output=%q", stdout.String())
+	}
+	d, err := ioutil.ReadFile(filepath.Join(out, "dede", "index.htm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(d), `<title><{gk:global name="cfg_webname"/}></title>`) {
+		t.Errorf("converted=%q", d)
+	}
+	if r, _ := ioutil.ReadFile(report); string(r) != stdout.String() {
+		t.Errorf("report=%q", r)
+	}
+}
+
+func TestGen(t *testing.T) {
+	out, err := ioutil.TempDir("", "gkgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	var stdout, stderr bytes.Buffer
+	file := filepath.Join(out, "views", "views.go")
+	code := run([]string{"gen", "-o", file, "-root", "testdata", "page.htm"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("gen exit %d: %s", code, stderr.String())
+	}
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"package views", `Name: "page.htm"`, "func renderPageHtm(r *gkt.Renderer) {"} {
+		if !strings.Contains(string(d), s) {
+			t.Errorf("generated code missing %q:\n%s", s, d)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	out, err := ioutil.TempDir("", "gkbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"build", "-o", out, "testdata/site/site.yaml"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("build exit %d: %s", code, stderr.String())
+	}
+	b, err := ioutil.ReadFile(filepath.Join(out, "list_2.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "2:b\n" {
+		t.Errorf("list_2.html=%q", string(b))
+	}
+	if !strings.Contains(stdout.String(), "2 pages, 2 written") {
+		t.Errorf("build output=%q", stdout.String())
+	}
+}
+
+func TestExtract(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"extract", "-merge", "testdata/i18n/en.json", "testdata/i18n/index.htm"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("extract exit %d: %s", code, stderr.String())
+	}
+	msgs, err := gkt.ParseJSONCatalog(stdout.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// items没有译文，读取时被忽略
+	if len(msgs) != 1 || msgs["welcome"][gkt.PluralOther] != "Welcome, {name}" {
+		t.Errorf("json catalog=%v", msgs)
+	}
+	if !strings.Contains(stdout.String(), `"items": {`) {
+		t.Errorf("json catalog should list untranslated items:\n%s", stdout.String())
+	}
+
+	// PO格式可以重新读取，合并已有的译文
+	out, err := ioutil.TempDir("", "gkextract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+	file := filepath.Join(out, "ru.po")
+	stdout.Reset()
+	code = run([]string{"extract", "-o", file, "-locale", "ru", "testdata/i18n/index.htm"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("extract exit %d: %s", code, stderr.String())
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	po := strings.Replace(string(b), `msgstr[2] ""`, `msgstr[2] "{count} статей"`, 1)
+	if !strings.Contains(string(b), "#: testdata/i18n/index.htm:1 testdata/i18n/index.htm:3\nmsgid \"welcome\"") ||
+		!strings.Contains(string(b), `"Plural-Forms: nplurals=3; plural=`) || strings.Contains(string(b), "msgstr[3]") {
+		t.Errorf("po catalog:\n%s", b)
+	}
+	ioutil.WriteFile(file, []byte(po), 0644)
+	stdout.Reset()
+	code = run([]string{"extract", "-format", "po", "-locale", "ru", "-merge", file, "testdata/i18n/index.htm"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("extract exit %d: %s", code, stderr.String())
+	}
+	msgs, err = gkt.ParsePO("ru", stdout.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs["items"][gkt.PluralMany] != "{count} статей" || msgs["items"][gkt.PluralOther] != "{count} статей" {
+		t.Errorf("merged po catalog:\n%s", stdout.String())
+	}
+}
+
+// 提取的消息目录直接加载后渲染，未翻译的消息输出key
+func TestExtractRoundTrip(t *testing.T) {
+	e := gkt.New()
+	e.SetDefaultLocale("en")
+	for _, c := range []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"extract", "testdata/i18n/index.htm"}, "<h1>welcome</h1>\nitems\n<p>welcome</p>\n"},
+		{[]string{"extract", "-merge", "testdata/i18n/en.json", "testdata/i18n/index.htm"}, "<h1>Welcome, GoKeep</h1>\nitems\n<p>Welcome, {name}</p>\n"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(c.args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("extract exit %d: %s", code, stderr.String())
+		}
+		msgs, err := gkt.ParseJSONCatalog(stdout.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.AddMessages("en", msgs)
+		rs, err := e.ParseFile("testdata/i18n/index.htm", gkt.D{"user": gkt.D{"name": "GoKeep"}, "list": []gkt.D{{"n": 2}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rs != c.expect {
+			t.Errorf("%v result=%q, expect %q", c.args, rs, c.expect)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+var (
+	renderData   *string
+	renderFormat *string
+)
+
+// gktemplate render [-data file] [-format json|yaml] template
+var cmdRender = &command{
+	Name: "render",
+	Init: func(fs *flag.FlagSet) {
+		renderData = fs.String("data", "", "JSON/YAML data file, - for stdin")
+		renderFormat = fs.String("format", "", "data format: json or yaml, default by file extension")
+	},
+	Run: func(e *gkt.Engine, fs *flag.FlagSet, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		if len(args) != 1 {
+			fmt.Fprintln(stderr, "usage: gktemplate render [-data file] [-format json|yaml] template")
+			return 2
+		}
+		data, err := loadData(*renderData, *renderFormat, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s: %s\n", *renderData, err)
+			return 1
+		}
+		rs, err := e.ParseFile(args[0], data)
+		if err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+		io.WriteString(stdout, rs)
+		return 0
+	},
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+// gktemplate tags pattern...
+var cmdTags = &command{
+	Name: "tags",
+	Run: func(e *gkt.Engine, fs *flag.FlagSet, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		if len(args) == 0 {
+			fmt.Fprintln(stderr, "usage: gktemplate tags pattern...")
+			return 2
+		}
+		files, err := expand(args)
+		if err != nil {
+			fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+			return 1
+		}
+
+		failed := false
+		for _, f := range files {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				fmt.Fprintf(stderr, "gktemplate: %s\n", err)
+				return 1
+			}
+			usages, diags := e.Inspect(f, string(b))
+			for _, d := range diags {
+				if d.Severity == gkt.SeverityError {
+					fmt.Fprintln(stderr, d)
+					failed = true
+				}
+			}
+			fmt.Fprintln(stdout, f)
+			tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+			for _, u := range usages {
+				kind := "tag"
+				if u.Block {
+					kind = "block"
+				}
+				if !u.Known {
+					kind += "(unknown)"
+				}
+				fmt.Fprintf(tw, "  %d:%d\t%s\t%s\tattrs=%s\tfunc=%s\n",
+					u.Line, u.Col, u.Tag, kind, strings.Join(u.Attrs, ","), u.Func)
+			}
+			tw.Flush()
+		}
+		if failed {
+			return 1
+		}
+		return 0
+	},
+}
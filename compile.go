@@ -0,0 +1,143 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板编译
+package gktemplate
+
+import (
+	"strings"
+
+	attr "github.com/gokeeptech/gktemplate/attribute"
+)
+
+// 编译后的模板
+// 文本片段直接引用模板字符串，literal块合并到文本中，标签处理函数、标签定义以及func属性在编译时确定，
+// 渲染时只需按顺序输出文本和执行标签
+type program struct {
+	engine  *Engine
+	version uint64 // 编译时引擎的标签、函数版本
+
+	texts []string // texts[i]为第i个标签之前的文本，最后一项为最后一个标签之后的文本
+	ops   []op     // 需要执行的标签
+	defs  []*GKTag // macro、import标签，渲染前注册
+	size  int      // 文本总长度
+	tail  int      // 最后一段文本的开始位置
+}
+
+// 编译后的标签
+type op struct {
+	tag    *GKTag
+	lib    TagLib        // 标签处理函数，nil表示[field:name/]或未注册的标签
+	schema *TagSchema    // 标签定义
+	fn     TagFunc       // func属性的函数
+	args   []interface{} // func属性的参数
+	me     []int         // 参数中@me的位置
+}
+
+// 获取模板的编译结果，引擎的标签、函数、标签定义变化后重新编译
+func (e *Engine) program(gktp *GKTemplate) *program {
+	v := e.version()
+	if p, ok := gktp.prog.Load().(*program); ok && p.engine == e && p.version == v {
+		return p
+	}
+	p := e.compile(gktp, v)
+	gktp.prog.Store(p)
+	return p
+}
+
+// 编译模板
+func (e *Engine) compile(gktp *GKTemplate, version uint64) *program {
+	p := &program{engine: e, version: version}
+	src := gktp.source
+	var text []string
+	next := 0
+	for i := 0; i < gktp.Count; i++ {
+		tag := gktp.CTags[i]
+		if end := tag.StartPos - tag.trimBefore; end > next {
+			text = append(text, src[next:end])
+		}
+		next = tag.EndPos + tag.trimAfter
+		if tag.literal {
+			text = append(text, tag.innerText)
+			continue
+		}
+		p.addText(text)
+		text = text[:0]
+		p.ops = append(p.ops, e.compileTag(gktp, tag))
+
+		if isDef(gktp, tag) {
+			p.defs = append(p.defs, tag)
+		}
+	}
+	if len(src) > next {
+		text = append(text, src[next:])
+	}
+	p.tail = next
+	p.addText(text)
+	return p
+}
+
+// macro、import标签在渲染前注册
+func isDef(gktp *GKTemplate, tag *GKTag) bool {
+	if tag.isField || tag.NameSpace != gktp.NameSpace {
+		return false
+	}
+	return tag.TagName == "macro" || tag.TagName == "import"
+}
+
+// 添加文本片段，只有一段时直接引用模板字符串，不复制
+func (p *program) addText(text []string) {
+	s := ""
+	switch len(text) {
+	case 0:
+	case 1:
+		s = text[0]
+	default:
+		s = strings.Join(text, "")
+	}
+	p.texts = append(p.texts, s)
+	p.size += len(s)
+}
+
+// 编译标签：查找标签处理函数、标签定义，解析func属性
+func (e *Engine) compileTag(gktp *GKTemplate, tag *GKTag) op {
+	o := op{tag: tag}
+	t := *tag
+	if !tag.isField {
+		o.schema = e.lookupSchema(gktp, tag)
+		o.lib, _ = e.lookupTag(gktp, tag)
+		t.schema = o.schema
+	}
+	if tplFunc := t.GetAttribute("func"); tplFunc != "" {
+		funcName, args, err := attr.FuncParser(tplFunc)
+		if err != nil {
+			return o
+		}
+		if o.fn, _ = e.funcs.Get(funcName); o.fn == nil {
+			return o
+		}
+		o.args = args
+		for i, arg := range args {
+			if arg == "@me" {
+				o.me = append(o.me, i)
+			}
+		}
+	}
+	return o
+}
+
+// 使用func属性处理值，参数中的@me替换为当前值
+func (o *op) call(v string) string {
+	if o.fn == nil {
+		return v
+	}
+	args := o.args
+	if len(o.me) > 0 {
+		args = make([]interface{}, len(o.args))
+		copy(args, o.args)
+		for _, i := range o.me {
+			args[i] = v
+		}
+	}
+	return o.fn(&v, args)
+}
@@ -0,0 +1,37 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板编译单元测试
+package gktemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+// 编译后的模板与未编译时的结果相同，标签、函数修改后重新编译
+func TestCompile(t *testing.T) {
+	e := New()
+	tpl := `a<{gk:literal}><{gk:field/}><{/gk:literal}>b <{-gk:field name="x" func="ToUpper(@me)"/-}> c<{gk:hi/}>d`
+	render := func() string {
+		rs, err := e.ParseStringWithNameSpace(&tpl, D{"x": "y"}, "", "", "", "compile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rs
+	}
+	if rs := render(); rs != "a<{gk:field/}>bYcd" {
+		t.Errorf("compile result=%q", rs)
+	}
+
+	e.RegisterLib("hi", func(tag *GKTag, data *D) string { return "hi" })
+	e.ReplaceFunc("ToUpper", func(v *string, args ...interface{}) string { return strings.Repeat(*v, 2) })
+	if rs := render(); rs != "a<{gk:field/}>byychid" {
+		t.Errorf("recompile result=%q", rs)
+	}
+
+	e.SetSchema("hi", &TagSchema{Attrs: []AttrSchema{{Name: "func", Default: "ToUpper(@me)"}}})
+	if rs := render(); rs != "a<{gk:field/}>byychihid" {
+		t.Errorf("schema recompile result=%q", rs)
+	}
+}
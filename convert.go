@@ -0,0 +1,241 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// DedeCMS模板转换
+package gktemplate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DedeCMS模板的命名空间和标记
+const (
+	dedeNameSpace = "dede"
+	dedeTagStart  = "{"
+	dedeTagEnd    = "}"
+)
+
+// 无法转换的Dede标签及原因
+var dedeUnsupportedTags = map[string]string{
+	"php": "PHP code is not supported",
+	"sql": "SQL query is not supported, register a tag that loads the data",
+}
+
+// 无法转换的Dede属性及原因
+var dedeUnsupportedAttrs = map[string]string{
+	"sql":    "SQL query is not supported, register a tag that loads the data",
+	"runphp": "PHP code is not supported",
+}
+
+// Dede模板转换器
+type dedeConverter struct {
+	engine *Engine
+	name   string
+	src    string
+	cfg    *parseConfig
+	ns     string // 目标命名空间
+	start  string // 目标标签开始标记
+	end    string // 目标标签结束标记
+	diags  []Diagnostic
+}
+
+func (c *dedeConverter) add(pos int, sev Severity, tag, format string, args ...interface{}) {
+	line, col := position(c.src, pos)
+	c.diags = append(c.diags, Diagnostic{
+		Name:     c.name,
+		Pos:      pos,
+		Line:     line,
+		Col:      col,
+		Severity: sev,
+		Tag:      tag,
+		Msg:      fmt.Sprintf(format, args...),
+	})
+}
+
+// 转换src[start:end]中的标签，块标签的内部文本递归转换
+// 解析失败时原样输出并记录错误
+func (c *dedeConverter) convert(sb *strings.Builder, start, end int, field bool) {
+	cfg := *c.cfg
+	cfg.Field = field
+	tpl, err := parseSource(c.src[start:end], &cfg)
+	if err != nil {
+		if pe, ok := err.(*ParseError); ok {
+			c.add(start+pe.Pos, SeverityError, "", "%s", pe.Msg)
+		} else {
+			c.add(start, SeverityError, "", "%s", err)
+		}
+		sb.WriteString(c.src[start:end])
+		return
+	}
+
+	next := start
+	for i := 0; i < tpl.Count; i++ {
+		tag := tpl.CTags[i]
+		pos := start + tag.StartPos
+		sb.WriteString(c.src[next:pos])
+		if tag.literal {
+			sb.WriteString(c.src[pos : start+tag.EndPos])
+			next = start + tag.EndPos
+			continue
+		}
+
+		// 标签头：开始标记之后到结束标记之前
+		tagStart, tagEnd := c.cfg.TagStart, c.cfg.TagEnd
+		ns, outStart, outEnd := c.ns, c.start, c.end
+		if tag.isField {
+			tagStart, tagEnd = fieldTagStart, fieldTagEnd
+			ns, outStart, outEnd = fieldNameSpace, fieldTagStart, fieldTagEnd
+		}
+		headEnd := start + tag.EndPos
+		if tag.InnerPos >= 0 {
+			headEnd = start + tag.InnerPos
+		}
+		body := c.src[pos+len(tagStart) : headEnd-len(tagEnd)]
+		body = body[strings.Index(body, ":")+1:]
+		if tag.InnerPos < 0 {
+			body = strings.TrimRight(strings.TrimSpace(body), "/")
+		}
+		name, body := c.convertBody(pos, tag, body)
+
+		sb.WriteString(outStart + ns + ":" + body)
+		if tag.InnerPos < 0 {
+			sb.WriteString("/" + outEnd)
+		} else {
+			sb.WriteString(outEnd)
+			c.convert(sb, start+tag.InnerPos, start+tag.InnerPos+len(tag.innerText), true)
+			sb.WriteString(outStart + "/" + ns + ":" + name + outEnd)
+		}
+		next = start + tag.EndPos
+	}
+	sb.WriteString(c.src[next:end])
+}
+
+// 转换标签名称和属性，返回转换后的标签名称和标签体
+func (c *dedeConverter) convertBody(pos int, tag *GKTag, body string) (string, string) {
+	body = strings.TrimSpace(body)
+	name, rest := body, ""
+	if i := strings.IndexAny(body, " \t\r\n"); i > 0 {
+		name, rest = body[:i], body[i:]
+	}
+	rest = renameAttr(rest, "function", "func")
+
+	full := dedeNameSpace + ":" + name
+	if tag.isField {
+		full = fieldNameSpace + ":" + name
+	} else if i := strings.Index(name, "."); i > 0 {
+		// field.title、global.cfg_webname
+		switch strings.ToLower(name[:i]) {
+		case "field", "global":
+			rest = " name=\"" + name[i+1:] + "\"" + rest
+			name = strings.ToLower(name[:i])
+		}
+	}
+
+	lname := strings.ToLower(name)
+	names := make([]string, 0, len(tag.CAttribute.Items))
+	for an := range tag.CAttribute.Items {
+		names = append(names, an)
+	}
+	sort.Strings(names)
+	for _, an := range names {
+		if msg, ok := dedeUnsupportedAttrs[an]; ok {
+			c.add(pos, SeverityError, full, "tag '%s' attribute '%s': %s", full, an, msg)
+		}
+	}
+	if msg, ok := dedeUnsupportedTags[lname]; ok {
+		c.add(pos, SeverityError, full, "tag '%s': %s", full, msg)
+	} else if !tag.isField {
+		if _, ok := c.engine.libs.Get(lname); !ok {
+			c.add(pos, SeverityWarning, full, "tag '%s' has no built-in equivalent, register tag '%s' before rendering", full, lname)
+		}
+	}
+	return name, name + rest
+}
+
+// 修改属性名称，忽略引号中的内容
+func renameAttr(s, from, to string) string {
+	var sb strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		r := s[i]
+		if quote != 0 {
+			sb.WriteByte(r)
+			if r == '\\' && i+1 < len(s) {
+				i++
+				sb.WriteByte(s[i])
+			} else if r == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '\'', '"', '`':
+			quote = r
+		default:
+			if (i == 0 || !isNameByte(s[i-1])) && strings.HasPrefix(s[i:], from) {
+				j := i + len(from)
+				k := j
+				for k < len(s) && (s[k] == ' ' || s[k] == '\t') {
+					k++
+				}
+				if k < len(s) && s[k] == '=' {
+					sb.WriteString(to)
+					i = j - 1
+					continue
+				}
+			}
+		}
+		sb.WriteByte(r)
+	}
+	return sb.String()
+}
+
+// 将DedeCMS模板转换为引擎当前的命名空间和标记，返回转换结果和按位置排序的诊断信息
+// `{dede:field.title function='cn_substr(@me,30)'/}`转换为`<{gk:field name="title" func='cn_substr(@me,30)'/}>`，
+// 使用PHP代码、SQL查询的标签记为错误，引擎中没有对应标签的记为警告
+func (e *Engine) ConvertDede(name, src string) (string, []Diagnostic) {
+	ns, start, end := e.NameSpace()
+	c := &dedeConverter{
+		engine: e,
+		name:   name,
+		src:    src,
+		cfg: &parseConfig{
+			Name:       name,
+			NameSpaces: []string{dedeNameSpace},
+			TagStart:   dedeTagStart,
+			TagEnd:     dedeTagEnd,
+		},
+		ns:    ns,
+		start: start,
+		end:   end,
+	}
+	var sb strings.Builder
+	c.convert(&sb, 0, len(c.src), false)
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		return c.diags[i].Pos < c.diags[j].Pos
+	})
+	return sb.String(), c.diags
+}
+
+// 转换DedeCMS模板文件并写入dst，目录不存在则创建
+func (e *Engine) ConvertDedeFile(src, dst string) ([]Diagnostic, error) {
+	d, err := ioutil.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	out, diags := e.ConvertDede(src, string(d))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return diags, err
+	}
+	return diags, ioutil.WriteFile(dst, []byte(out), 0644)
+}
+
+// 使用默认引擎转换DedeCMS模板
+func ConvertDede(name, src string) (string, []Diagnostic) {
+	return defaultEngine.ConvertDede(name, src)
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// DedeCMS模板转换单元测试
+package gktemplate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertDede(t *testing.T) {
+	e := New()
+	src, err := ioutil.ReadFile("testdata/dede/article.htm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, diags := e.ConvertDede("article.htm", string(src))
+	expect := `<title><{gk:field name="title"/}>_<{gk:global name="cfg_webname"/}></title>
+<style>body { color: red; }</style>
+<{gk:arclist row='10' titlelen='30'}>
+<li><a href="[field:arcurl/]">[field:title func='cn_substr(@me,30)'/]</a></li>
+<{/gk:arclist}>
+<{gk:field name='body' runphp='yes'}>@me = "x";<{/gk:field}>
+<{gk:pagelist listitem="pre,pageno,next" listsize="5"/}>
+`
+	if out != expect {
+		t.Errorf("convert result=%q, expect %q", out, expect)
+	}
+	if len(diags) != 2 {
+		t.Fatalf("diags=%v", diags)
+	}
+	if d := diags[0]; d.Line != 3 || d.Severity != SeverityWarning || d.Tag != "dede:arclist" {
+		t.Errorf("diag[0]=%s", d)
+	}
+	if d := diags[1]; d.Line != 6 || d.Col != 1 || d.Severity != SeverityError {
+		t.Errorf("diag[1]=%s", d)
+	}
+
+	// 转换结果可以直接校验
+	if ds := e.Validate("article.htm", out); len(ds) == 0 {
+		t.Errorf("converted template should report unknown tag arclist")
+	}
+}
+
+func TestConvertDedeFile(t *testing.T) {
+	out, err := ioutil.TempDir("", "gkdede")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	dst := filepath.Join(out, "theme", "article.htm")
+	if _, err := New().ConvertDedeFile("testdata/dede/article.htm", dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Error(err)
+	}
+}
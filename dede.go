@@ -0,0 +1,257 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// DedeCMS模板兼容
+package gktemplate
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	attr "github.com/gokeeptech/gktemplate/attribute"
+)
+
+// Dede兼容模式使用的函数库
+const dedeLibrary = "dede"
+
+// 设置DedeCMS兼容模式，修改后清空模板缓存
+// 兼容模式下：
+// `{dede:field.title/}`等同于`{dede:field name="title"/}`，`{dede:global.cfg_webname/}`等同于`{dede:global name="cfg_webname"/}`；
+// function属性作为func属性的别名，例：[field:title function='cn_substr(@me,30)'/]；
+// 注册cn_substr、MyDate等常用Dede函数，函数名称已存在则返回错误
+// 命名空间和标签标记仍使用SetNameSpace设置，例：e.SetNameSpace("dede", "{", "}")
+func (e *Engine) SetDedeCompat(on bool) error {
+	if on == e.DedeCompat() {
+		return nil
+	}
+	if on {
+		if err := e.Use(DedeLibrary()); err != nil {
+			return err
+		}
+	} else {
+		e.Unuse(dedeLibrary)
+	}
+	e.Lock()
+	e.dede = on
+	e.Unlock()
+	e.templates.Clear()
+	return nil
+}
+
+// 是否开启DedeCMS兼容模式
+func (e *Engine) DedeCompat() bool {
+	e.RLock()
+	defer e.RUnlock()
+	return e.dede
+}
+
+// 将Dede标签属性转换为gktemplate属性，解析后的属性有缓存，这里返回副本
+func dedeAttribute(cAtt *attr.Attribute) *attr.Attribute {
+	name := cAtt.GetTagName()
+	dotted := ""
+	if i := strings.Index(name, "."); i > 0 {
+		switch strings.ToLower(name[:i]) {
+		case "field", "global":
+			dotted = name[i+1:]
+			name = strings.ToLower(name[:i])
+		}
+	}
+	fn := cAtt.GetAtt("function")
+	if dotted == "" && (fn == "" || cAtt.IsAttribute("func")) {
+		return cAtt
+	}
+
+	items := make(map[string]string, len(cAtt.Items)+1)
+	for k, v := range cAtt.Items {
+		items[k] = v
+	}
+	if dotted != "" {
+		items["tagname"] = name
+		if items["name"] == "" {
+			items["name"] = dotted
+		}
+	}
+	if fn != "" && items["func"] == "" {
+		items["func"] = fn
+		delete(items, "function")
+	}
+	return &attr.Attribute{Count: len(items) - 1, Items: items}
+}
+
+// DedeLibrary 常用DedeCMS函数
+// cn_substr、cn_substrR按Dede的规则截取字符串，中文等非ASCII字符计2个长度；
+// MyDate、GetDateMK、GetDateTimeMK将Unix时间戳格式化为日期；
+// Html2Text、htmlspecialchars、trim、strtolower、strtoupper、urlencode、nl2br与PHP函数功能相同
+func DedeLibrary() *Library {
+	return &Library{
+		Name: dedeLibrary,
+		Funcs: map[string]TagFunc{
+			"cn_substr":        FuncCnSubstr,
+			"cn_substrR":       FuncCnSubstr,
+			"MyDate":           FuncMyDate,
+			"GetDateMK":        FuncGetDateMK,
+			"GetDateTimeMK":    FuncGetDateTimeMK,
+			"Html2Text":        FuncHtml2Text,
+			"html2text":        FuncHtml2Text,
+			"htmlspecialchars": FuncHtmlSpecialChars,
+			"trim":             FuncTrim,
+			"strtolower":       FuncToLower,
+			"strtoupper":       FuncToUpper,
+			"urlencode":        FuncUrlEncode,
+			"nl2br":            FuncNl2br,
+		},
+	}
+}
+
+// 获取函数参数，参数列表按TagFunc的约定整体作为第一个参数传入
+func funcArg(args []interface{}, i int) interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	list, _ := args[0].([]interface{})
+	if i < len(list) {
+		return list[i]
+	}
+	return nil
+}
+
+// 截取字符串，中文等非ASCII字符计2个长度，例：cn_substr(@me,30)
+func FuncCnSubstr(v *string, args ...interface{}) string {
+	s := toString(funcArg(args, 0))
+	limit := toInt(funcArg(args, 1))
+	if limit <= 0 {
+		return s
+	}
+	n := 0
+	for i, r := range s {
+		w := 1
+		if r >= 0x80 {
+			w = 2
+		}
+		if n+w > limit {
+			return s[:i]
+		}
+		n += w
+	}
+	return s
+}
+
+// PHP date格式对应的Go时间格式
+var phpDateLayout = map[rune]string{
+	'Y': "2006", 'y': "06",
+	'm': "01", 'n': "1",
+	'd': "02", 'j': "2",
+	'H': "15", 'h': "03", 'g': "3",
+	'i': "04", 's': "05",
+	'A': "PM", 'a': "pm",
+	'M': "Jan", 'D': "Mon",
+}
+
+// 按PHP date格式化时间
+func phpDate(format string, t time.Time) string {
+	var sb strings.Builder
+	escape := false
+	for _, r := range format {
+		if escape {
+			sb.WriteRune(r)
+			escape = false
+			continue
+		}
+		if r == '\\' {
+			escape = true
+			continue
+		}
+		switch r {
+		case 'G':
+			sb.WriteString(strconv.Itoa(t.Hour()))
+		default:
+			if layout, ok := phpDateLayout[r]; ok {
+				sb.WriteString(t.Format(layout))
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// 解析Unix时间戳，不是数字则返回false
+func unixTime(v interface{}) (time.Time, bool) {
+	switch n := v.(type) {
+	case time.Time:
+		return n, true
+	case int64:
+		return time.Unix(n, 0), true
+	case int:
+		return time.Unix(int64(n), 0), true
+	}
+	ts, err := strconv.ParseInt(strings.TrimSpace(toString(v)), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0), true
+}
+
+// 按PHP date格式输出时间戳，例：MyDate('Y-m-d',@me)
+func FuncMyDate(v *string, args ...interface{}) string {
+	t, ok := unixTime(funcArg(args, 1))
+	if !ok {
+		return *v
+	}
+	return phpDate(toString(funcArg(args, 0)), t)
+}
+
+// 时间戳格式化为Y-m-d，例：GetDateMK(@me)
+func FuncGetDateMK(v *string, args ...interface{}) string {
+	t, ok := unixTime(funcArg(args, 0))
+	if !ok {
+		return *v
+	}
+	return t.Format("2006-01-02")
+}
+
+// 时间戳格式化为Y-m-d H:i:s，例：GetDateTimeMK(@me)
+func FuncGetDateTimeMK(v *string, args ...interface{}) string {
+	t, ok := unixTime(funcArg(args, 0))
+	if !ok {
+		return *v
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+var reHTMLTag = regexp.MustCompile(`(?s)<[^>]*>`)
+
+// 去除HTML标签，例：Html2Text(@me)
+func FuncHtml2Text(v *string, args ...interface{}) string {
+	s := reHTMLTag.ReplaceAllString(toString(funcArg(args, 0)), "")
+	return strings.TrimSpace(html.UnescapeString(s))
+}
+
+// 转义HTML特殊字符，例：htmlspecialchars(@me)
+func FuncHtmlSpecialChars(v *string, args ...interface{}) string {
+	return html.EscapeString(toString(funcArg(args, 0)))
+}
+
+// 去除首尾空白，例：trim(@me)
+func FuncTrim(v *string, args ...interface{}) string {
+	return strings.TrimSpace(toString(funcArg(args, 0)))
+}
+
+// URL编码，例：urlencode(@me)
+func FuncUrlEncode(v *string, args ...interface{}) string {
+	return url.QueryEscape(toString(funcArg(args, 0)))
+}
+
+// 换行转换为<br />，例：nl2br(@me)
+func FuncNl2br(v *string, args ...interface{}) string {
+	return strings.Replace(toString(funcArg(args, 0)), "\n", "<br />\n", -1)
+}
+
+// 设置默认引擎的DedeCMS兼容模式
+func SetDedeCompat(on bool) error {
+	return defaultEngine.SetDedeCompat(on)
+}
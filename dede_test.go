@@ -0,0 +1,70 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// DedeCMS兼容模式单元测试
+package gktemplate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedeCompat(t *testing.T) {
+	e := New()
+	e.SetNameSpace("dede", "{", "}")
+	if err := e.SetDedeCompat(true); err != nil {
+		t.Fatal(err)
+	}
+	e.SetGlobal("cfg_webname", "GoKeep")
+
+	ts := time.Date(2020, 5, 1, 8, 30, 0, 0, time.Local).Unix()
+	tpl := `<title>{dede:field.title/}_{dede:global.cfg_webname/}</title>
+<a href="{dede:field name='typeurl'/}">{dede:field.typename function="strtoupper(@me)"/}</a>
+{dede:range name="list"}<li>[field:title function='cn_substr(@me,7)'/] [field:pubdate function="MyDate('Y-m-d H:i',@me)"/]</li>{/dede:range}`
+	data := D{
+		"title":    "GoKeep模板引擎",
+		"typeurl":  "/news/",
+		"typename": "news",
+		"list":     []D{{"title": "中文标题测试", "pubdate": ts}},
+	}
+	rs, err := e.ParseString(tpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `<title>GoKeep模板引擎_GoKeep</title>
+<a href="/news/">NEWS</a>
+<li>中文标 2020-05-01 08:30</li>`
+	if rs != expect {
+		t.Errorf("dede result=%q, expect %q", rs, expect)
+	}
+
+	// 关闭兼容模式后不再识别field.title
+	if err := e.SetDedeCompat(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.LookupFunc("cn_substr"); ok {
+		t.Errorf("dede funcs should be removed")
+	}
+	rs, _ = e.ParseString(`{dede:field.title/}`, data)
+	if rs != "" {
+		t.Errorf("dede disabled result=%q", rs)
+	}
+}
+
+func TestFuncCnSubstr(t *testing.T) {
+	cases := []struct {
+		s     string
+		limit string
+		out   string
+	}{
+		{"hello world", "5", "hello"},
+		{"中文abc", "5", "中文a"},
+		{"中文abc", "3", "中"},
+		{"short", "0", "short"},
+	}
+	for _, c := range cases {
+		if out := FuncCnSubstr(&c.s, []interface{}{c.s, c.limit}); out != c.out {
+			t.Errorf("cn_substr(%q,%s)=%q, expect %q", c.s, c.limit, out, c.out)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// Package gkecho Echo框架的模板渲染器
+//
+// 独立的模块，模板引擎本身不依赖Echo：
+//
+//	e := echo.New()
+//	e.Renderer = gkecho.New(engine)
+//	e.GET("/", func(c echo.Context) error {
+//		return c.Render(200, "simple.htm", echo.Map{"info": "GoKeep"})
+//	})
+package gkecho
+
+import (
+	"io"
+
+	gkt "github.com/gokeeptech/gktemplate"
+	"github.com/labstack/echo/v4"
+)
+
+// Renderer echo.Renderer的实现
+type Renderer struct {
+	engine *gkt.Engine
+}
+
+// 创建渲染器，e为nil时使用默认引擎
+func New(e *gkt.Engine) *Renderer {
+	if e == nil {
+		e = gkt.Default()
+	}
+	return &Renderer{engine: e}
+}
+
+// 获取模板引擎
+func (rd *Renderer) Engine() *gkt.Engine {
+	return rd.engine
+}
+
+// 渲染模板并写入w，出错时不写入内容
+// data可以是echo.Map、gkt.D或map[string]interface{}，其他类型作为data变量传给模板
+func (rd *Renderer) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
+	rs, err := rd.engine.Render(name, toData(data))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, rs)
+	return err
+}
+
+func toData(data interface{}) gkt.D {
+	switch v := data.(type) {
+	case nil:
+		return gkt.D{}
+	case gkt.D:
+		return v
+	case echo.Map:
+		return gkt.D(v)
+	case map[string]interface{}:
+		return gkt.D(v)
+	default:
+		return gkt.D{"data": v}
+	}
+}
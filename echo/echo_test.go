@@ -0,0 +1,65 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// Echo渲染器单元测试
+package gkecho
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gkt "github.com/gokeeptech/gktemplate"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRender(t *testing.T) {
+	files := map[string]string{
+		"simple.htm": `<h1><{gk:field name="info"/}></h1>`,
+		"struct.htm": `<{gk:field name="data"/}>`,
+		"bad.htm":    `<{gk:call name="missing"/}>`,
+	}
+	engine := gkt.New()
+	engine.SetLoader(gkt.LoaderFunc(func(name string) (string, error) {
+		return files[name], nil
+	}))
+
+	e := echo.New()
+	e.Renderer = New(engine)
+	e.GET("/", func(c echo.Context) error {
+		return c.Render(http.StatusOK, "simple.htm", echo.Map{"info": "GoKeep"})
+	})
+	e.GET("/d", func(c echo.Context) error {
+		return c.Render(http.StatusCreated, "simple.htm", gkt.D{"info": "D"})
+	})
+	e.GET("/struct", func(c echo.Context) error {
+		return c.Render(http.StatusOK, "struct.htm", 42)
+	})
+	e.GET("/bad", func(c echo.Context) error {
+		return c.Render(http.StatusOK, "bad.htm", nil)
+	})
+
+	for _, c := range []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/", http.StatusOK, "<h1>GoKeep</h1>"},
+		{"/d", http.StatusCreated, "<h1>D</h1>"},
+		{"/struct", http.StatusOK, "42"},
+	} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+		if w.Code != c.code || w.Body.String() != c.body || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("%s code=%d body=%q type=%q, want %d %q", c.path, w.Code, w.Body.String(), w.Header().Get("Content-Type"), c.code, c.body)
+		}
+	}
+
+	// 渲染错误由Echo的错误处理返回500
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/bad", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "<h1>") {
+		t.Errorf("/bad code=%d body=%q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,441 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板引擎实例
+package gktemplate
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Engine 模板引擎，包含标签设置、标签/函数注册表、全局变量以及模板缓存
+// 包级别的函数（Parse、ParseFile、ExtLibs等）均作用于默认引擎
+type Engine struct {
+	schemaVersion uint64 // 标签定义修改次数，已编译的模板据此重新查找标签定义
+
+	nameSpace string // 默认命名空间，例：gk
+	tagStart  string // 标签开始标记，例：<{
+	tagEnd    string // 标签结束标记，例：}>
+
+	libs      *libRegistry           // 模板标签，非默认命名空间的标签以`ns:name`存储
+	funcs     *funcRegistry          // 模板函数
+	libraries map[string]*Library    // 已注册的标签库
+	schemas   map[string]*TagSchema  // 标签定义
+	loaded    []string               // LoadDir加载的模板文件
+	globals   map[string]interface{} // 全局变量
+	dede      bool                   // DedeCMS兼容模式
+	trim      bool                   // 去掉独占一行的块标签所在行的缩进和换行
+	loader    Loader                 // 模板加载器
+	limits    Limits                 // 单次渲染的资源限制
+	cache     FragmentCache          // 片段缓存
+
+	catalogs catalogStorage // 多语言消息目录
+	locale   string         // 默认语言
+
+	goTemplate  *template.Template // gotpl标签的Go模板集合
+	goTemplates goTemplateStorage  // gotpl标签解析后的内联Go模板
+
+	templates templateStorage     // 解析后的模板缓存
+	files     templateFileStorage // 模板文件缓存
+	nss       atomic.Value        // 命名空间列表缓存*nameSpaceCache
+	compiled  compiledStorage     // 生成代码的模板
+
+	sync.RWMutex
+}
+
+// 创建模板引擎，内置field、range、pagelist、set、with、global、macro、call、import、switch、cache、gotpl、t标签以及ToUpper、ToLower函数
+func New() *Engine {
+	e := &Engine{
+		nameSpace: defaultNameSpace,
+		tagStart:  defaultTagStart,
+		tagEnd:    defaultTagEnd,
+		libs:      newLibRegistry(),
+		funcs:     newFuncRegistry(),
+		libraries: make(map[string]*Library),
+		schemas:   make(map[string]*TagSchema),
+		globals:   make(map[string]interface{}),
+		loader:    FileLoader{},
+		cache:     NewMemoryCache(DefaultCacheSize),
+	}
+	e.templates.Items = make(map[string]*GKTemplate)
+	e.files.Items = make(map[string]*string)
+	e.goTemplates.Items = make(map[string]*template.Template)
+	e.catalogs.Items = make(map[string]Messages)
+
+	e.libs.Items["field"] = TagField
+	e.libs.Items["range"] = TagRange
+	e.libs.Items["pagelist"] = TagPageList
+	e.libs.Items["set"] = TagSet
+	e.libs.Items["with"] = TagWith
+	e.libs.Items["global"] = TagGlobal
+	e.libs.Items["macro"] = TagMacro
+	e.libs.Items["call"] = TagCall
+	e.libs.Items["import"] = TagImport
+	e.libs.Items["switch"] = TagSwitch
+	e.libs.Items["case"] = TagCase
+	e.libs.Items["default"] = TagCase
+	e.libs.Items["cache"] = TagCache
+	e.libs.Items["gotpl"] = TagGoTpl
+	e.libs.Items["t"] = TagT
+	e.schemas["field"] = schemaField
+	e.schemas["range"] = schemaRange
+	e.schemas["pagelist"] = schemaPageList
+	e.schemas["set"] = schemaSet
+	e.schemas["with"] = schemaWith
+	e.schemas["global"] = schemaGlobal
+	e.schemas["macro"] = schemaMacro
+	e.schemas["call"] = schemaCall
+	e.schemas["import"] = schemaImport
+	e.schemas["switch"] = schemaSwitch
+	e.schemas["case"] = schemaCase
+	e.schemas["default"] = schemaDefault
+	e.schemas["cache"] = schemaCache
+	e.schemas["gotpl"] = schemaGoTpl
+	e.schemas["t"] = schemaT
+
+	e.funcs.Items["ToUpper"] = FuncToUpper
+	e.funcs.Items["ToLower"] = FuncToLower
+	return e
+}
+
+var defaultEngine *Engine // 默认引擎
+
+func init() {
+	defaultEngine = New()
+}
+
+// 获取默认引擎
+func Default() *Engine {
+	return defaultEngine
+}
+
+// 设置标签，修改后清空模板缓存
+func (e *Engine) SetNameSpace(ns, start, end string) {
+	if ns != "" && start != "" && (start != end) {
+		e.Lock()
+		e.nameSpace = ns
+		e.tagStart = start
+		e.tagEnd = end
+		e.Unlock()
+		e.templates.Clear()
+	}
+}
+
+// 获取标签设置
+func (e *Engine) NameSpace() (ns, start, end string) {
+	e.RLock()
+	defer e.RUnlock()
+	return e.nameSpace, e.tagStart, e.tagEnd
+}
+
+// 设置是否去掉独占一行的块标签、注释所在行的缩进和换行，修改后清空模板缓存
+func (e *Engine) SetTrimBlocks(on bool) {
+	e.Lock()
+	e.trim = on
+	e.Unlock()
+	e.templates.Clear()
+}
+
+// 是否去掉独占一行的块标签、注释所在行的缩进和换行
+func (e *Engine) TrimBlocks() bool {
+	e.RLock()
+	defer e.RUnlock()
+	return e.trim
+}
+
+// 命名空间列表缓存，标签注册表变化后重新生成
+type nameSpaceCache struct {
+	version uint64   // 生成时标签注册表的修改次数
+	ns      string   // 默认命名空间
+	list    []string // 可识别的命名空间
+}
+
+// 当前可识别的命名空间，第一个为默认命名空间
+// 标签库的命名空间只在使用引擎标签标记时生效
+// 返回的列表在模板之间共享，不能修改
+func (e *Engine) nameSpaces(ns, start, end string) []string {
+	if _, estart, eend := e.NameSpace(); start != estart || end != eend {
+		return []string{ns}
+	}
+	version := atomic.LoadUint64(&e.libs.version)
+	if c, ok := e.nss.Load().(*nameSpaceCache); ok && c.version == version && c.ns == ns {
+		return c.list
+	}
+	nss := []string{ns}
+	seen := map[string]bool{ns: true}
+	for _, name := range e.libs.Names() {
+		i := strings.Index(name, ":")
+		if i <= 0 || seen[name[:i]] {
+			continue
+		}
+		seen[name[:i]] = true
+		nss = append(nss, name[:i])
+	}
+	e.nss.Store(&nameSpaceCache{version: version, ns: ns, list: nss})
+	return nss
+}
+
+// 模板源码和标签设置的摘要，解析时计算一次，保存在缓存的模板中
+func sourceVersion(syntax, src string) string {
+	h := sha1.New()
+	h.Write([]byte(syntax))
+	h.Write([]byte(src))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// 标签设置的字符串形式，用于模板缓存键和生成代码
+func syntaxString(nss []string, start, end string, dede, trim bool) string {
+	syntax := strings.Join(nss, ",") + "|" + start + "|" + end
+	if dede {
+		syntax += "|dede"
+	}
+	if trim {
+		syntax += "|trim"
+	}
+	return syntax
+}
+
+// 获取标签处理函数
+func (e *Engine) lookupTag(tpl *GKTemplate, tag *GKTag) (TagLib, bool) {
+	if tag.NameSpace == "" || tag.NameSpace == tpl.NameSpace {
+		return e.libs.Get(tag.TagName)
+	}
+	return e.libs.Get(tag.NameSpace + ":" + tag.TagName)
+}
+
+// 标签、函数、标签定义的修改次数，变化后已编译的模板重新编译
+func (e *Engine) version() uint64 {
+	return atomic.LoadUint64(&e.libs.version) + atomic.LoadUint64(&e.funcs.version) + atomic.LoadUint64(&e.schemaVersion)
+}
+
+// 命名空间标签变化时需要重新解析模板
+func (e *Engine) libsChanged(name string) {
+	if strings.Contains(name, ":") {
+		e.templates.Clear()
+	}
+}
+
+// 注册模板标签，名称已存在则返回错误
+// 名称可以带命名空间，例如`shop:price`
+func (e *Engine) RegisterLib(name string, lib TagLib) error {
+	if err := e.libs.Add(name, lib); err != nil {
+		return err
+	}
+	e.libsChanged(name)
+	return nil
+}
+
+// 注册或覆盖模板标签，返回被覆盖的标签
+func (e *Engine) ReplaceLib(name string, lib TagLib) (TagLib, error) {
+	old, err := e.libs.Set(name, lib)
+	if err != nil {
+		return nil, err
+	}
+	e.libsChanged(name)
+	return old, nil
+}
+
+// 删除模板标签及其定义，返回标签是否存在
+func (e *Engine) UnregisterLib(name string) bool {
+	ok := e.libs.Delete(name)
+	if ok {
+		e.SetSchema(name, nil)
+		e.libsChanged(name)
+	}
+	return ok
+}
+
+// 获取模板标签
+func (e *Engine) LookupLib(name string) (TagLib, bool) {
+	return e.libs.Get(name)
+}
+
+// 列出已注册的模板标签
+func (e *Engine) Libs() []string {
+	return e.libs.Names()
+}
+
+// 注册模板函数，名称已存在则返回错误
+func (e *Engine) RegisterFunc(name string, f TagFunc) error {
+	return e.funcs.Add(name, f)
+}
+
+// 注册或覆盖模板函数，返回被覆盖的函数
+func (e *Engine) ReplaceFunc(name string, f TagFunc) (TagFunc, error) {
+	return e.funcs.Set(name, f)
+}
+
+// 删除模板函数，返回函数是否存在
+func (e *Engine) UnregisterFunc(name string) bool {
+	return e.funcs.Delete(name)
+}
+
+// 获取模板函数
+func (e *Engine) LookupFunc(name string) (TagFunc, bool) {
+	return e.funcs.Get(name)
+}
+
+// 列出已注册的模板函数
+func (e *Engine) Funcs() []string {
+	return e.funcs.Names()
+}
+
+// 支持模板自定义扩展标签，标签已存在则panic
+// 覆盖已有标签请使用ReplaceLib
+func (e *Engine) ExtLibs(libs map[string]TagLib) {
+	if err := e.libs.AddAll(libs); err != nil {
+		panic(fmt.Sprintf("[GKTemplate]ExtLibs:%s", err))
+	}
+	for name := range libs {
+		e.libsChanged(name)
+	}
+}
+
+// 支持模板自定义扩展函数，函数已存在则panic
+// 覆盖已有函数请使用ReplaceFunc
+func (e *Engine) ExtFuncs(funcs map[string]TagFunc) {
+	if err := e.funcs.AddAll(funcs); err != nil {
+		panic(fmt.Sprintf("[GKTemplate]ExtFuncs:%s", err))
+	}
+}
+
+// 解析模板，优先从缓存中获取
+func (e *Engine) parseTemplate(name string, tplstr *string, nameSpace, tagStart, tagEnd, cachekey string) (*GKTemplate, error) {
+	ens, estart, eend := e.NameSpace()
+	if tagStart == "" {
+		tagStart = estart
+	}
+	if tagEnd == "" {
+		tagEnd = eend
+	}
+	if nameSpace == "" {
+		nameSpace = ens
+	}
+	nss := e.nameSpaces(nameSpace, tagStart, tagEnd)
+
+	// 缓存键需要包含标签设置
+	dede, trim := e.DedeCompat(), e.TrimBlocks()
+	syntax := syntaxString(nss, tagStart, tagEnd, dede, trim)
+	// 指定了缓存键时命中缓存不需要计算模板摘要
+	khash, version := "", ""
+	if cachekey != "" {
+		khash = cachekey + "|" + syntax
+	} else {
+		version = sourceVersion(syntax, *tplstr)
+		khash = version
+	}
+
+	v := e.templates.GetTemplate(khash)
+	if v != nil {
+		// 存在缓存则直接返回缓存
+		return v, nil
+	}
+	if version == "" {
+		version = sourceVersion(syntax, *tplstr)
+	}
+
+	gktpl, err := parseSource(*tplstr, &parseConfig{
+		Name:       name,
+		NameSpaces: nss,
+		TagStart:   tagStart,
+		TagEnd:     tagEnd,
+		Dede:       dede,
+		TrimBlocks: trim,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 编译后再放入缓存，之后的渲染直接使用编译结果
+	gktpl.version = version
+	e.program(gktpl)
+	e.templates.SetTemplate(khash, gktpl)
+
+	return gktpl, nil
+}
+
+// 加载目录中的文件到文件缓存，然后使用Parse方法直接渲染
+// 某个文件出错时继续加载其余文件，返回第一个错误，Lint可以校验所有文件
+func (e *Engine) LoadDir(pattern string) error {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return errNoneFileInDir
+	}
+
+	var first error
+	for _, f := range matches {
+		if isDirectory(f) {
+			continue
+		}
+		e.addLoaded(f)
+		if _, err := e.ParseFile(f, nil); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// 清空模板缓存和模板文件缓存，之后的渲染重新读取、解析模板
+// 开发时修改模板文件后调用，GKENV=dev时不使用缓存
+func (e *Engine) ClearCache() {
+	e.templates.Clear()
+	e.files.Clear()
+	e.goTemplates.Clear()
+}
+
+// 记录加载的模板文件
+func (e *Engine) addLoaded(filename string) {
+	e.Lock()
+	defer e.Unlock()
+	for _, f := range e.loaded {
+		if f == filename {
+			return
+		}
+	}
+	e.loaded = append(e.loaded, filename)
+}
+
+// 列出LoadDir加载的模板文件
+func (e *Engine) Loaded() []string {
+	e.RLock()
+	defer e.RUnlock()
+	return append([]string(nil), e.loaded...)
+}
+
+// 解析文件
+func (e *Engine) Parse(filename string, data D) (string, error) {
+	return e.ParseFile(filename, data)
+}
+
+// 解析文件，文件通过引擎的加载器读取
+func (e *Engine) ParseFile(filename string, data D) (string, error) {
+	gktp, err := e.loadTemplate(filename)
+	if err != nil {
+		return "", err
+	}
+	return e.render(gktp, data)
+}
+
+// 解析字符串
+func (e *Engine) ParseString(tplstr string, data D) (string, error) {
+	return e.ParseStringWithNameSpace(&tplstr, data, "", "", "", "")
+}
+
+// 指定标记名称解析字符串
+func (e *Engine) ParseStringWithNameSpace(tplstr *string, data D, nameSpace, tagStart, tagEnd, cachekey string) (string, error) {
+	// 解析模板
+	gktp, err := e.parseTemplate("", tplstr, nameSpace, tagStart, tagEnd, cachekey)
+	if err != nil {
+		return "", err
+	}
+	return e.render(gktp, data)
+}
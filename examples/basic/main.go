@@ -4,9 +4,10 @@
 package main
 
 import (
-	"fmt"
-	gkt "github.com/gokeeptech/gktemplate"
 	"net/http"
+
+	gkt "github.com/gokeeptech/gktemplate"
+	gkhttp "github.com/gokeeptech/gktemplate/http"
 )
 
 func main() {
@@ -17,13 +18,8 @@ func main() {
 		data := gkt.D{
 			"info": "Template engine for GoKeep(GK)，GoKeep模板引擎",
 		}
-		// 渲染模板
-		rs, err := gkt.Parse("templates/simple.htm", data)
-		if err != nil {
-			fmt.Fprintf(w, err.Error())
-			return
-		}
-		fmt.Fprintf(w, rs)
+		// 渲染模板，出错时返回500错误页面
+		gkhttp.Render(w, r, "templates/simple.htm", data)
 	})
 
 	http.ListenAndServe(":8088", nil)
@@ -4,9 +4,10 @@
 package main
 
 import (
-	"fmt"
-	gkt "github.com/gokeeptech/gktemplate"
 	"net/http"
+
+	gkt "github.com/gokeeptech/gktemplate"
+	gkhttp "github.com/gokeeptech/gktemplate/http"
 )
 
 func main() {
@@ -14,7 +15,7 @@ func main() {
 	gkt.SetNameSpace("llgoer", "{", "}")
 
 	// 使用扩展函数
-	var funcs = make(map[string]gkt.TagFunc)
+	var funcs = make(map[string]gkt.TagLib)
 
 	// test标签
 	funcs["test"] = func(tag *gkt.GKTag, data *gkt.D) string {
@@ -23,7 +24,7 @@ func main() {
 	}
 
 	// 扩展自定义标签函数
-	gkt.ExtLibs(&funcs)
+	gkt.ExtLibs(funcs)
 
 	// 加载模板
 	gkt.LoadDir("./templates/*.htm")
@@ -33,13 +34,8 @@ func main() {
 			"info": "Template engine for GoKeep(GK)，GoKeep模板引擎",
 		}
 
-		// 渲染模板
-		rs, err := gkt.Parse("templates/simple.htm", data)
-		if err != nil {
-			fmt.Fprintf(w, err.Error())
-			return
-		}
-		fmt.Fprintf(w, rs)
+		// 渲染模板，出错时返回500错误页面
+		gkhttp.Render(w, r, "templates/simple.htm", data)
 	})
 
 	http.ListenAndServe(":8088", nil)
@@ -4,14 +4,13 @@
 package main
 
 import (
+	"os"
+
 	"github.com/gin-gonic/gin"
 	gkt "github.com/gokeeptech/gktemplate"
-	"log"
-	"os"
+	gkgin "github.com/gokeeptech/gktemplate/gin"
 )
 
-const CTYPE = "text/html; charset=utf-8"
-
 func init() {
 	// 开发模式下启用日志调试
 	if os.Getenv("GKENV") != "dev" {
@@ -21,18 +20,14 @@ func init() {
 
 func main() {
 	r := gin.Default()
-	// 加载模板
-	gkt.LoadDir("./templates/*.htm")
+	// 使用GKTemplate渲染模板，调试模式下修改模板后无需重启
+	rd := gkgin.New(gkt.Default())
+	rd.SetReload(gin.IsDebugging())
+	r.HTMLRender = rd
 	r.GET("/", func(c *gin.Context) {
-		data := gkt.D{
+		c.HTML(200, "templates/simple.htm", gin.H{
 			"info": "Template engine for GoKeep(GK)，GoKeep模板引擎",
-		}
-		// 渲染模板
-		rs, err := gkt.Parse("templates/simple.htm", data)
-		if err != nil {
-			log.Println(err)
-		}
-		c.Data(200, CTYPE, []byte(rs))
+		})
 	})
 	r.Run()
 }
@@ -0,0 +1,306 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板生成Go代码
+package gktemplate
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	attr "github.com/gokeeptech/gktemplate/attribute"
+)
+
+// CompiledFunc 生成的模板渲染函数
+type CompiledFunc func(r *Renderer)
+
+// CompiledTag 生成代码中的标签，渲染时据此构造标签，不需要解析模板源码
+type CompiledTag struct {
+	NameSpace string            // 命名空间
+	Name      string            // 标签名称
+	Attrs     map[string]string // 属性
+	Start     int               // 标签开始位置
+	End       int               // 标签结束位置
+	InnerPos  int               // 内部文本开始位置，-1表示自闭合标签
+	Inner     string            // 内部文本，块标签首次渲染时解析
+}
+
+// Compiled 生成代码的模板，由生成的包通过RegisterCompiled注册到引擎
+type Compiled struct {
+	Name   string        // 模板名称，与Render使用的名称相同
+	Source string        // 模板源码，用于错误定位
+	Syntax string        // 生成代码时的标签设置
+	Tags   []CompiledTag // 渲染函数中Tag(i)执行的标签
+	Render CompiledFunc  // 渲染函数
+}
+
+// 注册到引擎的生成代码，模板在首次渲染时由生成的标签构造
+type compiledTemplate struct {
+	c    *Compiled
+	once sync.Once
+	tpl  *GKTemplate
+}
+
+// 下面定义一个存储结构体，保存注册的生成代码
+type compiledStorage struct {
+	Items map[string]*compiledTemplate // 存储结构
+	sync.RWMutex
+}
+
+func (as *compiledStorage) SetCompiled(k string, v *compiledTemplate) {
+	as.Lock()
+	defer as.Unlock()
+	if as.Items == nil {
+		as.Items = make(map[string]*compiledTemplate)
+	}
+	as.Items[k] = v
+}
+
+// 获取生成代码的模板，开发模式下不使用生成代码
+func (as *compiledStorage) GetCompiled(k string) *compiledTemplate {
+	if os.Getenv("GKENV") == "dev" {
+		return nil
+	}
+	as.RLock()
+	defer as.RUnlock()
+	return as.Items[k]
+}
+
+// 注册生成代码的模板，同名模板被覆盖
+func (e *Engine) RegisterCompiled(c *Compiled) {
+	e.compiled.SetCompiled(c.Name, &compiledTemplate{c: c})
+}
+
+// 由生成的标签构造模板，标签设置使用引擎当前的设置，即与生成时相同
+func (e *Engine) compiledTemplate(ct *compiledTemplate) *GKTemplate {
+	ct.once.Do(func() {
+		ns, start, end := e.NameSpace()
+		tpl := &GKTemplate{
+			Name:       ct.c.Name,
+			NameSpace:  ns,
+			NameSpaces: e.nameSpaces(ns, start, end),
+			TagStart:   start,
+			TagEnd:     end,
+			CTags:      make(map[int]*GKTag, len(ct.c.Tags)),
+			Count:      len(ct.c.Tags),
+			source:     ct.c.Source,
+			root:       ct.c.Source,
+			dede:       e.DedeCompat(),
+			trimBlocks: e.TrimBlocks(),
+		}
+		for i, t := range ct.c.Tags {
+			items := make(map[string]string, len(t.Attrs)+1)
+			for k, v := range t.Attrs {
+				items[k] = v
+			}
+			items["tagname"] = t.Name
+			tag := &GKTag{
+				NameSpace:  t.NameSpace,
+				TagName:    t.Name,
+				CAttribute: &attr.Attribute{Count: len(t.Attrs), Items: items},
+				InnerPos:   t.InnerPos,
+				StartPos:   t.Start,
+				EndPos:     t.End,
+				TagID:      i,
+				innerText:  t.Inner,
+				tpl:        tpl,
+			}
+			if t.InnerPos >= 0 {
+				tag.inner = &innerTemplate{}
+			}
+			tpl.CTags[i] = tag
+		}
+		ct.tpl = tpl
+	})
+	return ct.tpl
+}
+
+// 生成代码的编译结果，不解析模板源码，引擎的标签、函数、标签定义变化后重新编译
+func (e *Engine) compiledProgram(gktp *GKTemplate) *program {
+	v := e.version()
+	if p, ok := gktp.prog.Load().(*program); ok && p.version == v {
+		return p
+	}
+	// 文本由渲染函数直接输出，size只用于预分配
+	p := &program{engine: e, version: v, size: len(gktp.source)}
+	for i := 0; i < gktp.Count; i++ {
+		tag := gktp.CTags[i]
+		p.ops = append(p.ops, e.compileTag(gktp, tag))
+		if isDef(gktp, tag) {
+			p.defs = append(p.defs, tag)
+		}
+		p.tail = tag.EndPos
+	}
+	gktp.prog.Store(p)
+	return p
+}
+
+// Renderer 生成的渲染函数使用的渲染状态
+type Renderer struct {
+	ctx  *renderContext
+	p    *program
+	data *D
+	tag  GKTag
+	sb   strings.Builder
+}
+
+// 输出文本
+func (r *Renderer) Text(s string) {
+	r.sb.WriteString(s)
+}
+
+// 执行第i个标签并输出结果，出错或超过资源限制时返回false，渲染函数应立即返回
+func (r *Renderer) Tag(i int) bool {
+	return r.ctx.exec(&r.p.ops[i], &r.tag, r.data, &r.sb)
+}
+
+// 引擎的标签设置，包括标签库的命名空间，生成代码时记录，渲染时设置不同则不使用生成代码
+func (e *Engine) syntaxKey() string {
+	ns, start, end := e.NameSpace()
+	return syntaxString(e.nameSpaces(ns, start, end), start, end, e.DedeCompat(), e.TrimBlocks())
+}
+
+// 渲染模板，注册了生成代码且标签设置一致时使用生成代码，否则通过加载器读取模板解析渲染
+func (e *Engine) Render(name string, data D) (string, error) {
+	if ct := e.compiled.GetCompiled(name); ct != nil && ct.c.Syntax == e.syntaxKey() {
+		gktp := e.compiledTemplate(ct)
+		return e.renderCompiled(gktp, e.compiledProgram(gktp), ct.c, data)
+	}
+	return e.ParseFile(name, data)
+}
+
+func (e *Engine) renderCompiled(gktp *GKTemplate, p *program, c *Compiled, data D) (string, error) {
+	ctx, scope := e.newContext(data)
+	ctx.define(p.defs, scope)
+	r := &Renderer{ctx: ctx, p: p, data: scope}
+	r.sb.Grow(p.size)
+	c.Render(r)
+	if ctx.err == nil {
+		ctx.checkOutput(&GKTag{tpl: gktp, StartPos: p.tail}, r.sb.Len())
+	}
+	if ctx.err != nil {
+		return "", ctx.err
+	}
+	return r.sb.String(), nil
+}
+
+// 将模板生成为Go代码，每个模板生成一个渲染函数，生成的包在init中注册到引擎
+// 模板通过加载器读取，names为Render使用的模板名称
+func (e *Engine) GenerateGo(pkg string, names []string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gktemplate gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	buf.WriteString("import gkt \"github.com/gokeeptech/gktemplate\"\n\n")
+	buf.WriteString("func init() {\n\tRegister(gkt.Default())\n}\n\n")
+	buf.WriteString("// Register 将生成的模板注册到引擎\n")
+
+	syntax := e.syntaxKey()
+	funcs := make(map[string]bool)
+	var body bytes.Buffer
+	buf.WriteString("func Register(e *gkt.Engine) {\n")
+	for i, name := range names {
+		gktp, err := e.loadTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		p := e.program(gktp)
+
+		fn := funcName(name)
+		for funcs[fn] {
+			fn += strconv.Itoa(i)
+		}
+		funcs[fn] = true
+		src := "src" + strings.TrimPrefix(fn, "render")
+		tags := "tags" + strings.TrimPrefix(fn, "render")
+
+		fmt.Fprintf(&buf, "\te.RegisterCompiled(&gkt.Compiled{Name: %q, Source: %s, Syntax: %q, Tags: %s, Render: %s})\n",
+			name, src, syntax, tags, fn)
+
+		fmt.Fprintf(&body, "\n// %s\nconst %s = %s\n\n", name, src, strconv.Quote(gktp.source))
+		fmt.Fprintf(&body, "var %s = []gkt.CompiledTag{\n", tags)
+		for _, o := range p.ops {
+			writeCompiledTag(&body, o.tag)
+		}
+		body.WriteString("}\n\n")
+		fmt.Fprintf(&body, "func %s(r *gkt.Renderer) {\n", fn)
+		for j, text := range p.texts {
+			if text != "" {
+				fmt.Fprintf(&body, "\tr.Text(%s)\n", strconv.Quote(text))
+			}
+			if j == len(p.ops) {
+				break
+			}
+			tag := p.ops[j].tag
+			line, col := position(gktp.source, tag.StartPos)
+			fmt.Fprintf(&body, "\t// %d:%d %s:%s\n\tif !r.Tag(%d) {\n\t\treturn\n\t}\n", line, col, tag.NameSpace, tag.TagName, j)
+		}
+		body.WriteString("}\n")
+	}
+	buf.WriteString("}\n")
+	buf.Write(body.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// 输出生成代码中的标签
+func writeCompiledTag(w *bytes.Buffer, tag *GKTag) {
+	fmt.Fprintf(w, "\t{NameSpace: %q, Name: %q, Attrs: map[string]string{", tag.NameSpace, tag.TagName)
+	names := make([]string, 0, len(tag.CAttribute.Items))
+	for k := range tag.CAttribute.Items {
+		if k != "tagname" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for i, k := range names {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		fmt.Fprintf(w, "%q: %q", k, tag.CAttribute.Items[k])
+	}
+	fmt.Fprintf(w, "}, Start: %d, End: %d, InnerPos: %d", tag.StartPos, tag.EndPos, tag.InnerPos)
+	if tag.InnerPos >= 0 {
+		fmt.Fprintf(w, ", Inner: %s", strconv.Quote(tag.innerText))
+	}
+	w.WriteString("},\n")
+}
+
+// 模板名称转换为渲染函数名称，例：news/index.htm转换为renderNewsIndexHtm
+func funcName(name string) string {
+	var sb strings.Builder
+	sb.WriteString("render")
+	upper := true
+	for _, r := range name {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// 将生成代码的模板注册到默认引擎，同名模板被覆盖
+func RegisterCompiled(c *Compiled) {
+	defaultEngine.RegisterCompiled(c)
+}
+
+// 使用默认引擎渲染模板
+func Render(name string, data D) (string, error) {
+	return defaultEngine.Render(name, data)
+}
+
+// 使用默认引擎将模板生成为Go代码
+func GenerateGo(pkg string, names []string) ([]byte, error) {
+	return defaultEngine.GenerateGo(pkg, names)
+}
@@ -0,0 +1,36 @@
+// Code generated by gktemplate gen. DO NOT EDIT.
+
+package gktemplate_test
+
+import gkt "github.com/gokeeptech/gktemplate"
+
+func init() {
+	Register(gkt.Default())
+}
+
+// Register 将生成的模板注册到引擎
+func Register(e *gkt.Engine) {
+	e.RegisterCompiled(&gkt.Compiled{Name: "testdata/gen/index.htm", Source: srcTestdataGenIndexHtm, Syntax: "gk|<{|}>", Tags: tagsTestdataGenIndexHtm, Render: renderTestdataGenIndexHtm})
+}
+
+// testdata/gen/index.htm
+const srcTestdataGenIndexHtm = "<h1><{gk:field name=\"title\" func=\"ToUpper(@me)\"/}></h1>\n<{gk:literal}><{gk:field/}><{/gk:literal}>\n<ul>\n<{gk:range name=\"list\"}>\n\t<li>[field:name/]</li>\n<{/gk:range}>\n</ul>\n"
+
+var tagsTestdataGenIndexHtm = []gkt.CompiledTag{
+	{NameSpace: "gk", Name: "field", Attrs: map[string]string{"func": "ToUpper(@me)", "name": "title"}, Start: 4, End: 50, InnerPos: -1},
+	{NameSpace: "gk", Name: "range", Attrs: map[string]string{"name": "list"}, Start: 104, End: 166, InnerPos: 128, Inner: "\n\t<li>[field:name/]</li>\n"},
+}
+
+func renderTestdataGenIndexHtm(r *gkt.Renderer) {
+	r.Text("<h1>")
+	// 1:5 gk:field
+	if !r.Tag(0) {
+		return
+	}
+	r.Text("</h1>\n<{gk:field/}>\n<ul>\n")
+	// 4:1 gk:range
+	if !r.Tag(1) {
+		return
+	}
+	r.Text("\n</ul>\n")
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 生成Go代码单元测试
+package gktemplate
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// gen_generated_test.go由GenerateGo生成，模板修改后需要重新生成
+func TestGenerateGo(t *testing.T) {
+	code, err := New().GenerateGo("gktemplate_test", []string{"testdata/gen/index.htm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect, err := ioutil.ReadFile("gen_generated_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(code) != string(expect) {
+		t.Errorf("generated code:\n%s", code)
+	}
+}
+
+// gen_generated_test.go在init中注册到默认引擎
+func TestRenderCompiled(t *testing.T) {
+	name := "testdata/gen/index.htm"
+	data := D{"title": "news", "list": []D{{"name": "a"}, {"name": "b"}}}
+	e := Default()
+	expect, err := New().ParseFile(name, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 生成代码不读取模板文件
+	e.SetLoader(LoaderFunc(func(name string) (string, error) {
+		return "", errors.New("not found")
+	}))
+	defer e.SetLoader(FileLoader{})
+	rs, err := e.Render(name, data)
+	if err != nil || rs != expect {
+		t.Errorf("compiled result=%q, err=%v, expect %q", rs, err, expect)
+	}
+
+	e.SetLimits(Limits{MaxIterations: 1})
+	if _, err := e.Render(name, data); err == nil {
+		t.Error("compiled render should apply limits")
+	}
+	e.SetLimits(Limits{})
+
+	// 注册的标签变化后重新编译
+	old, _ := e.ReplaceLib("field", func(tag *GKTag, data *D) string {
+		return "[" + tag.GetAttribute("name") + "]"
+	})
+	rs, _ = e.Render(name, data)
+	e.ReplaceLib("field", old)
+	if !strings.HasPrefix(rs, "<h1>[TITLE]</h1>") {
+		t.Errorf("compiled result after ReplaceLib=%q", rs)
+	}
+
+	// 标签设置不同时使用解释器
+	e.SetTrimBlocks(true)
+	_, err = e.Render(name, data)
+	e.SetTrimBlocks(false)
+	if err == nil {
+		t.Error("render with different syntax should fall back to the loader")
+	}
+
+	// 生成代码只注册到默认引擎
+	other := New()
+	other.SetLoader(LoaderFunc(func(name string) (string, error) {
+		return "", errors.New("not found")
+	}))
+	if _, err := other.Render(name, data); err == nil {
+		t.Error("compiled templates should be registered per engine")
+	}
+}
+
+// 生成代码与解释器渲染同一模板
+// go test -bench=Compiled -run=none -benchmem
+func BenchmarkRenderCompiled(b *testing.B) {
+	name := "testdata/gen/index.htm"
+	data := D{"title": "news", "list": []D{{"name": "a"}, {"name": "b"}}}
+	b.Run("compiled", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Render(name, data)
+		}
+	})
+	b.Run("interpreter", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ParseFile(name, data)
+		}
+	})
+}
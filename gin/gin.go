@@ -0,0 +1,139 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// Package gkgin Gin框架的模板渲染器
+//
+// 独立的模块，模板引擎本身不依赖Gin：
+//
+//	r := gin.Default()
+//	r.HTMLRender = gkgin.New(engine)
+//	r.GET("/", func(c *gin.Context) {
+//		c.HTML(200, "simple.htm", gin.H{"info": "GoKeep"})
+//	})
+//
+// 设置布局模板后页面的渲染结果作为content变量传给布局模板：
+//
+//	rd := gkgin.New(engine)
+//	rd.SetLayout("layout.htm") // 布局模板中使用<{gk:field name="content"/}>输出页面
+//	c.HTML(200, "about.htm", gin.H{"layout": "simple_layout.htm"}) // 单个页面指定布局，空字符串表示不使用布局
+package gkgin
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+// 默认的Content-Type
+const ContentTypeHTML = "text/html; charset=utf-8"
+
+const (
+	LayoutKey  = "layout"  // 渲染数据中指定布局模板的键
+	ContentKey = "content" // 布局模板中页面内容的变量名称
+)
+
+// Renderer Gin的HTMLRender实现
+type Renderer struct {
+	engine *gkt.Engine
+	layout string // 默认布局模板
+	reload bool   // 每次渲染前清空模板缓存
+	sync.RWMutex
+}
+
+// 创建渲染器，e为nil时使用默认引擎
+// 默认使用模板缓存，开发时可以调用SetReload(true)在每次渲染前重新读取模板
+func New(e *gkt.Engine) *Renderer {
+	if e == nil {
+		e = gkt.Default()
+	}
+	return &Renderer{engine: e}
+}
+
+// 获取模板引擎
+func (rd *Renderer) Engine() *gkt.Engine {
+	return rd.engine
+}
+
+// 设置默认布局模板，为空则不使用布局
+func (rd *Renderer) SetLayout(name string) {
+	rd.Lock()
+	defer rd.Unlock()
+	rd.layout = name
+}
+
+// 设置是否每次渲染前清空模板缓存，清空会影响同一引擎的所有模板，只应在开发时开启
+func (rd *Renderer) SetReload(on bool) {
+	rd.Lock()
+	defer rd.Unlock()
+	rd.reload = on
+}
+
+// 实现render.HTMLRender，data可以是gin.H、gkt.D或map[string]interface{}，其他类型作为data变量传给模板
+func (rd *Renderer) Instance(name string, data interface{}) render.Render {
+	rd.RLock()
+	defer rd.RUnlock()
+	h := &HTML{Renderer: rd, Name: name, Data: toData(data), Layout: rd.layout}
+	if v, ok := h.Data[LayoutKey]; ok {
+		h.Layout, _ = v.(string)
+	}
+	if rd.reload {
+		rd.engine.ClearCache()
+	}
+	return h
+}
+
+func toData(data interface{}) gkt.D {
+	switch v := data.(type) {
+	case nil:
+		return gkt.D{}
+	case gkt.D:
+		return v
+	case gin.H:
+		return gkt.D(v)
+	case map[string]interface{}:
+		return gkt.D(v)
+	default:
+		return gkt.D{"data": v}
+	}
+}
+
+// HTML 一次模板渲染，实现render.Render
+type HTML struct {
+	Renderer *Renderer
+	Name     string // 模板名称
+	Data     gkt.D  // 渲染数据
+	Layout   string // 布局模板，为空则不使用布局
+}
+
+// 渲染模板并写入响应，出错时不写入内容
+func (h *HTML) Render(w http.ResponseWriter) error {
+	e := h.Renderer.engine
+	rs, err := e.Render(h.Name, h.Data)
+	if err != nil {
+		return err
+	}
+	if h.Layout != "" {
+		data := make(gkt.D, len(h.Data)+1)
+		for k, v := range h.Data {
+			data[k] = v
+		}
+		data[ContentKey] = rs
+		if rs, err = e.Render(h.Layout, data); err != nil {
+			return err
+		}
+	}
+	h.WriteContentType(w)
+	_, err = io.WriteString(w, rs)
+	return err
+}
+
+// 设置Content-Type
+func (h *HTML) WriteContentType(w http.ResponseWriter) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", ContentTypeHTML)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// Gin渲染器单元测试
+package gkgin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+func newTestServer(files map[string]string, setup func(rd *Renderer)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	e := gkt.New()
+	e.SetLoader(gkt.LoaderFunc(func(name string) (string, error) {
+		return files[name], nil
+	}))
+	rd := New(e)
+	if setup != nil {
+		setup(rd)
+	}
+	r := gin.New()
+	r.HTMLRender = rd
+	return r
+}
+
+func get(r *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w
+}
+
+func TestHTML(t *testing.T) {
+	files := map[string]string{
+		"simple.htm": `<h1><{gk:field name="info"/}></h1>`,
+		"struct.htm": `<{gk:field name="data"/}>`,
+		"bad.htm":    `<{gk:call name="missing"/}>`,
+	}
+	r := newTestServer(files, nil)
+	r.GET("/", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "simple.htm", gin.H{"info": "GoKeep"})
+	})
+	r.GET("/d", func(c *gin.Context) {
+		c.HTML(http.StatusCreated, "simple.htm", gkt.D{"info": "D"})
+	})
+	r.GET("/struct", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "struct.htm", 42)
+	})
+	r.GET("/bad", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "bad.htm", nil)
+		if len(c.Errors) == 0 {
+			t.Error("expected render error")
+		}
+	})
+
+	for _, c := range []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/", http.StatusOK, "<h1>GoKeep</h1>"},
+		{"/d", http.StatusCreated, "<h1>D</h1>"},
+		{"/struct", http.StatusOK, "42"},
+		{"/bad", http.StatusOK, ""},
+	} {
+		w := get(r, c.path)
+		if w.Code != c.code || w.Body.String() != c.body {
+			t.Errorf("%s code=%d body=%q, want %d %q", c.path, w.Code, w.Body.String(), c.code, c.body)
+		}
+		if c.body != "" && w.Header().Get("Content-Type") != ContentTypeHTML {
+			t.Errorf("%s content type=%q", c.path, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	files := map[string]string{
+		"layout.htm":  `<main><{gk:field name="content"/}></main><{gk:field name="title"/}>`,
+		"simple.htm":  `<{gk:field name="title"/}>`,
+		"layout2.htm": `<div><{gk:field name="content"/}></div>`,
+	}
+	r := newTestServer(files, func(rd *Renderer) {
+		rd.SetLayout("layout.htm")
+	})
+	r.GET("/", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "simple.htm", gin.H{"title": "a"})
+	})
+	r.GET("/other", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "simple.htm", gin.H{"title": "b", LayoutKey: "layout2.htm"})
+	})
+	r.GET("/none", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "simple.htm", gin.H{"title": "c", LayoutKey: ""})
+	})
+	for path, want := range map[string]string{
+		"/":      "<main>a</main>a",
+		"/other": "<div>b</div>",
+		"/none":  "c",
+	} {
+		if rs := get(r, path).Body.String(); rs != want {
+			t.Errorf("%s result=%q, want %q", path, rs, want)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	files := map[string]string{"simple.htm": "v1"}
+	for _, reload := range []bool{false, true} {
+		files["simple.htm"] = "v1"
+		r := newTestServer(files, func(rd *Renderer) {
+			rd.SetReload(reload)
+		})
+		r.GET("/", func(c *gin.Context) {
+			c.HTML(http.StatusOK, "simple.htm", nil)
+		})
+		get(r, "/")
+		files["simple.htm"] = "v2"
+		want := "v1"
+		if reload {
+			want = "v2"
+		}
+		if rs := get(r, "/").Body.String(); rs != want {
+			t.Errorf("reload=%v result=%q, want %q", reload, rs, want)
+		}
+	}
+}
+
+// 调试模式下也默认使用模板缓存
+func TestReloadDefault(t *testing.T) {
+	defer gin.SetMode(gin.TestMode)
+	gin.SetMode(gin.DebugMode)
+	if rd := New(gkt.New()); rd.reload {
+		t.Errorf("reload should be off by default")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"text/template"
 )
@@ -101,6 +102,45 @@ func TestParseFile(t *testing.T) {
 	fmt.Println("[TestParseFile]result=", rs)
 }
 
+// 测试清空缓存后重新读取模板
+func TestClearCache(t *testing.T) {
+	e := New()
+	src := "v1"
+	e.SetLoader(LoaderFunc(func(name string) (string, error) {
+		return src, nil
+	}))
+	e.ParseFile("a.htm", nil)
+	src = "v2"
+	if rs, _ := e.ParseFile("a.htm", nil); rs != "v1" {
+		t.Errorf("cached result=%q", rs)
+	}
+	e.ClearCache()
+	if rs, _ := e.ParseFile("a.htm", nil); rs != "v2" {
+		t.Errorf("reloaded result=%q", rs)
+	}
+}
+
+// 模板版本在解析时计算，指定缓存键时命中缓存直接返回
+func TestTemplateVersionCached(t *testing.T) {
+	e := New()
+	src := "v1"
+	tpl, err := e.parseTemplate("", &src, "", "", "", "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.version != sourceVersion(e.syntaxKey(), "v1") {
+		t.Errorf("version=%s", tpl.version)
+	}
+	src2 := "v2"
+	if cached, _ := e.parseTemplate("", &src2, "", "", "", "k"); cached != tpl {
+		t.Errorf("cache key should return the cached template")
+	}
+	other, _ := e.parseTemplate("", &src2, "", "", "", "")
+	if other.version == tpl.version {
+		t.Errorf("version should change with the source")
+	}
+}
+
 // 测试加载目录
 func TestLoadDir(t *testing.T) {
 	// 载入模板
@@ -181,7 +221,7 @@ func TestExtLibs(t *testing.T) {
 		return "string from testtag name is:" + name
 	}
 
-	ExtLibs(&funcs)
+	ExtLibs(funcs)
 
 	rs, err := ParseFile("./testdata/tpl_extlibs.htm", data)
 	if err != nil {
@@ -325,3 +365,60 @@ func BenchmarkGoTemplate(b *testing.B) {
 		t.Execute(ioutil.Discard, data)
 	}
 }
+
+var benchtpl = `<html><head><title><{gk:field name="title"/}></title></head>
+<body>
+<h1><{gk:field name="title" func="ToUpper(@me)"/}></h1>
+<ul>
+<{gk:range name="items"}>
+	<li><a href="/news/[field:id/].html">[field:name/]</a> <span>[field:name func="ToLower(@me)"/]</span></li>
+<{/gk:range}>
+</ul>
+<p>GoKeep template engine, <{gk:field name="info"/}></p>
+</body></html>
+`
+
+func benchData() D {
+	items := make([]D, 20)
+	for i := range items {
+		items[i] = D{"id": i, "name": "GoKeep"}
+	}
+	return D{"title": "News", "info": "Template engine for GoKeep(GK)", "items": items}
+}
+
+// 渲染已缓存的模板，模板编译后渲染时不再查找标签、解析func属性
+func BenchmarkRender(b *testing.B) {
+	e := New()
+	data := benchData()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.ParseStringWithNameSpace(&benchtpl, data, "", "", "", "bench")
+	}
+}
+
+// 解析大模板，模板不使用缓存
+// 解析器直接处理UTF-8字节，不生成模板和内部文本的[]rune副本
+func BenchmarkParseLarge(b *testing.B) {
+	src := strings.Repeat("<p>GoKeep模板引擎，"+benchtpl+"</p>\n", 50)
+	cfg := &parseConfig{NameSpaces: []string{defaultNameSpace}, TagStart: defaultTagStart, TagEnd: defaultTagEnd}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parseSource(src, cfg)
+	}
+}
+
+// 外层作用域变量较多时渲染range，内部作用域不复制外层数据，耗时不随外层变量数量增长
+func BenchmarkRenderLargeScope(b *testing.B) {
+	e := New()
+	data := benchData()
+	for i := 0; i < 200; i++ {
+		data[fmt.Sprintf("var%d", i)] = i
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.ParseStringWithNameSpace(&benchtpl, data, "", "", "", "bench")
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 全局变量
+package gktemplate
+
+import (
+	"sort"
+	"strings"
+)
+
+// 全局变量前缀，例：<{gk:field name="global.webname"/}>
+const globalPrefix = "global."
+
+// GlobalFunc 延迟求值的全局变量，每次渲染最多调用一次
+type GlobalFunc func() interface{}
+
+var schemaGlobal = &TagSchema{
+	Kind: TagSelfClosing,
+	Attrs: []AttrSchema{
+		{Name: "name", Required: true},
+	},
+}
+
+// 设置全局变量，所有模板渲染时都可以通过global标签或`global.`前缀访问
+// v为nil则删除全局变量
+func (e *Engine) SetGlobal(name string, v interface{}) {
+	e.Lock()
+	defer e.Unlock()
+	if v == nil {
+		delete(e.globals, name)
+		return
+	}
+	if f, ok := v.(func() interface{}); ok {
+		v = GlobalFunc(f)
+	}
+	e.globals[name] = v
+}
+
+// 设置延迟求值的全局变量，例如当前年份
+func (e *Engine) SetGlobalFunc(name string, f GlobalFunc) {
+	if f == nil {
+		e.SetGlobal(name, nil)
+		return
+	}
+	e.SetGlobal(name, f)
+}
+
+// 删除全局变量，返回全局变量是否存在
+func (e *Engine) DeleteGlobal(name string) bool {
+	e.Lock()
+	defer e.Unlock()
+	_, ok := e.globals[name]
+	delete(e.globals, name)
+	return ok
+}
+
+// 获取全局变量，延迟求值的全局变量返回调用结果
+func (e *Engine) Global(name string) (interface{}, bool) {
+	e.RLock()
+	v, ok := e.globals[name]
+	e.RUnlock()
+	if f, isFunc := v.(GlobalFunc); isFunc {
+		v = f()
+	}
+	return v, ok
+}
+
+// 列出全局变量名称
+func (e *Engine) Globals() []string {
+	e.RLock()
+	defer e.RUnlock()
+	names := make([]string, 0, len(e.globals))
+	for name := range e.globals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 获取全局变量，延迟求值的结果在本次渲染中缓存
+func (ctx *renderContext) global(name string) (interface{}, bool) {
+	if v, ok := ctx.globals[name]; ok {
+		return v, true
+	}
+	v, ok := ctx.engine.Global(name)
+	if ok {
+		if ctx.globals == nil {
+			ctx.globals = make(map[string]interface{})
+		}
+		ctx.globals[name] = v
+	}
+	return v, ok
+}
+
+// 从作用域中取值，块标签内部的作用域中找不到时继续查找外层作用域；
+// `global.`开头的名称先从最外层的渲染数据查找，不存在再读取全局变量，
+// 即渲染数据中的同名变量会覆盖全局变量，range、with等块标签内部的变量不会
+// 例：global.webname、global.site.name
+func (gktag *GKTag) Lookup(data *D, name string) interface{} {
+	ctx := gktag.ctx
+	if _, ok := ctx.scopeValue(data, name); ok || !strings.HasPrefix(name, globalPrefix) {
+		return lookupScope(ctx, data, name)
+	}
+
+	name = name[len(globalPrefix):]
+	first, rest := name, ""
+	if i := strings.Index(name, "."); i > 0 {
+		first, rest = name[:i], name[i+1:]
+	}
+	var v interface{}
+	ok := false
+	if root := ctx.root(data); root != nil {
+		v, ok = (*root)[first]
+	}
+	if !ok {
+		v, ok = gktag.context().global(first)
+		if !ok {
+			return nil
+		}
+	}
+	if rest == "" {
+		return v
+	}
+	return lookup(D{first: v}, first+"."+rest)
+}
+
+// 解析global标签内容
+// 例：<{gk:global name="webname"/}>，等同于<{gk:field name="global.webname"/}>
+func TagGlobal(tag *GKTag, data *D) string {
+	return toString(tag.Lookup(data, globalPrefix+tag.GetAttribute("name")))
+}
+
+// 设置默认引擎的全局变量
+func SetGlobal(name string, v interface{}) {
+	defaultEngine.SetGlobal(name, v)
+}
+
+// 设置默认引擎延迟求值的全局变量
+func SetGlobalFunc(name string, f GlobalFunc) {
+	defaultEngine.SetGlobalFunc(name, f)
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 全局变量单元测试
+package gktemplate
+
+import (
+	"testing"
+)
+
+func TestGlobal(t *testing.T) {
+	e := New()
+	calls := 0
+	e.SetGlobal("webname", "GoKeep")
+	e.SetGlobal("site", D{"cdn": "//cdn.example.com"})
+	e.SetGlobalFunc("year", func() interface{} {
+		calls++
+		return 2020
+	})
+
+	tpl := `<{gk:global name="webname"/}>|<{gk:field name="global.site.cdn"/}>|` +
+		`<{gk:global name="year"/}>-<{gk:field name="global.year"/}>|` +
+		`<{gk:range name="list"}>[field:global.webname/]<{/gk:range}>|<{gk:field name="webname"/}>`
+	rs, err := e.ParseString(tpl, D{"list": []D{{"webname": "item"}, {}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// range内部的变量不覆盖全局变量，不带global.前缀不读取全局变量
+	if expect := "GoKeep|//cdn.example.com|2020-2020|GoKeepGoKeep|"; rs != expect {
+		t.Errorf("global result=%q, expect %q", rs, expect)
+	}
+	if calls != 1 {
+		t.Errorf("global func called %d times in one render", calls)
+	}
+
+	// 只有最外层的渲染数据覆盖全局变量
+	rs, _ = e.ParseString(`<{gk:global name="webname"/}>|<{gk:with name="item"}><{gk:field name="global.webname"/}><{/gk:with}>|`+
+		`<{gk:range name="list"}><{gk:global name="webname"/}><{/gk:range}>`,
+		D{"webname": "page", "item": D{"webname": "item"}, "list": []D{{"webname": "item"}}})
+	if rs != "page|page|page" {
+		t.Errorf("global shadow result=%q", rs)
+	}
+	rs, _ = e.ParseString(`<{gk:with name="item"}><{gk:field name="global.webname"/}><{/gk:with}>`, D{"item": D{"webname": "item"}})
+	if rs != "GoKeep" {
+		t.Errorf("global in with result=%q", rs)
+	}
+
+	if !e.DeleteGlobal("webname") || e.DeleteGlobal("webname") {
+		t.Errorf("DeleteGlobal failed")
+	}
+	if names := e.Globals(); len(names) != 2 || names[0] != "site" || names[1] != "year" {
+		t.Errorf("Globals=%v", names)
+	}
+}
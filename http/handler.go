@@ -0,0 +1,48 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// URL路径映射到模板
+package gkhttp
+
+import (
+	"net/http"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+// DataFunc 根据请求生成渲染数据
+type DataFunc func(r *http.Request) gkt.D
+
+// 创建按URL路径渲染模板的Handler，routes为路径到模板名称的映射
+// data为nil时不传递数据，并使用模板版本生成ETag；只接受GET、HEAD请求，未映射的路径返回404错误页面
+// 例：http.Handle("/", rd.Handler(map[string]string{"/": "index.htm", "/about": "about.htm"}, nil))
+func (rd *Renderer) Handler(routes map[string]string, data DataFunc) http.Handler {
+	m := make(map[string]string, len(routes))
+	for path, name := range routes {
+		m[path] = name
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			rd.Error(w, r, http.StatusMethodNotAllowed)
+			return
+		}
+		name, ok := m[r.URL.Path]
+		if !ok {
+			rd.Error(w, r, http.StatusNotFound)
+			return
+		}
+		p := Page{Name: name}
+		if data != nil {
+			p.Data = data(r)
+		} else {
+			p.Version = "static"
+		}
+		rd.Page(w, r, p)
+	})
+}
+
+// 使用默认引擎创建按URL路径渲染模板的Handler
+func Handler(routes map[string]string, data DataFunc) http.Handler {
+	return defaultRenderer.Handler(routes, data)
+}
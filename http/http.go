@@ -0,0 +1,233 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// Package gkhttp 在net/http中渲染模板
+//
+// Render渲染模板并写入响应，出错时返回对应的状态码并使用错误模板渲染错误页面：
+//
+//	gkhttp.Render(w, r, "index.htm", data)
+//
+// Handler将URL路径映射到模板，适用于关于我们、联系方式等简单页面：
+//
+//	http.Handle("/", rd.Handler(map[string]string{"/": "index.htm", "/about": "about.htm"}, nil))
+//
+// 支持根据模板版本和数据版本生成ETag、响应If-None-Match条件请求以及整页缓存：
+//
+//	rd := gkhttp.New(engine)
+//	rd.RenderPage(w, r, gkhttp.Page{
+//		Name:     "article.htm",
+//		Data:     data,
+//		Version:  article.UpdatedAt.String(),
+//		CacheKey: "article:" + id,
+//		TTL:      time.Minute,
+//	})
+package gkhttp
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+// 默认的Content-Type
+const ContentTypeHTML = "text/html; charset=utf-8"
+
+// Renderer 模板的HTTP渲染器
+type Renderer struct {
+	engine    *gkt.Engine
+	cache     gkt.FragmentCache // 整页缓存
+	errorPage string            // 错误页面模板
+	sync.RWMutex
+}
+
+// 创建HTTP渲染器，e为nil时使用默认引擎，整页缓存默认使用内存缓存
+func New(e *gkt.Engine) *Renderer {
+	if e == nil {
+		e = gkt.Default()
+	}
+	return &Renderer{
+		engine: e,
+		cache:  gkt.NewMemoryCache(gkt.DefaultCacheSize),
+	}
+}
+
+// 获取模板引擎
+func (rd *Renderer) Engine() *gkt.Engine {
+	return rd.engine
+}
+
+// 设置整页缓存，nil表示不缓存
+func (rd *Renderer) SetCache(c gkt.FragmentCache) {
+	rd.Lock()
+	defer rd.Unlock()
+	rd.cache = c
+}
+
+// 设置错误页面模板，渲染数据为status（状态码）和message（状态说明），为空则输出纯文本
+// 例：SetErrorTemplate("error.htm")，模板中使用<{gk:field name="status"/}>
+func (rd *Renderer) SetErrorTemplate(name string) {
+	rd.Lock()
+	defer rd.Unlock()
+	rd.errorPage = name
+}
+
+func (rd *Renderer) pageCache() gkt.FragmentCache {
+	rd.RLock()
+	defer rd.RUnlock()
+	return rd.cache
+}
+
+// 渲染模板并写入响应，Content-Type为text/html
+// 出错时记录日志并写入错误页面：模板不存在返回404，其他错误返回500，错误信息不会输出给客户端
+func (rd *Renderer) Render(w http.ResponseWriter, r *http.Request, name string, data gkt.D) error {
+	return rd.Page(w, r, Page{Name: name, Data: data})
+}
+
+// 与RenderPage相同，出错时写入错误页面
+func (rd *Renderer) Page(w http.ResponseWriter, r *http.Request, p Page) error {
+	err := rd.RenderPage(w, r, p)
+	if err != nil {
+		log.Printf("[GKTemplate]render %s: %s", p.Name, err)
+		w.Header().Del("ETag")
+		rd.Error(w, r, statusOf(err))
+	}
+	return err
+}
+
+// 错误对应的状态码
+func statusOf(err error) int {
+	if os.IsNotExist(err) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// 写入错误页面，设置了错误模板则使用模板渲染，否则输出状态说明
+func (rd *Renderer) Error(w http.ResponseWriter, r *http.Request, status int) {
+	rd.RLock()
+	page := rd.errorPage
+	rd.RUnlock()
+	if page != "" {
+		rs, err := rd.engine.ParseFile(page, gkt.D{"status": status, "message": http.StatusText(status)})
+		if err == nil {
+			w.Header().Set("Content-Type", ContentTypeHTML)
+			w.WriteHeader(status)
+			io.WriteString(w, rs)
+			return
+		}
+		log.Printf("[GKTemplate]render error page %s: %s", page, err)
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// 删除整页缓存
+func (rd *Renderer) Invalidate(key string) {
+	if c := rd.pageCache(); c != nil {
+		c.Delete(key)
+	}
+}
+
+// 删除指定前缀的整页缓存
+func (rd *Renderer) InvalidatePrefix(prefix string) {
+	if c := rd.pageCache(); c != nil {
+		c.DeletePrefix(prefix)
+	}
+}
+
+// Page 页面渲染参数
+type Page struct {
+	Name     string        // 模板名称
+	Data     gkt.D         // 渲染数据
+	Version  string        // 数据版本，例如文章的更新时间，为空则不生成ETag
+	CacheKey string        // 整页缓存键，为空则不缓存
+	TTL      time.Duration // 整页缓存时间，0表示不过期
+}
+
+// 渲染页面并写入响应
+// 设置Version时使用模板版本和数据版本生成ETag，请求的If-None-Match匹配则返回304；
+// 设置CacheKey时缓存渲染结果，缓存的ETag与当前不同则重新渲染。渲染出错时不写入响应，返回错误
+func (rd *Renderer) RenderPage(w http.ResponseWriter, r *http.Request, p Page) error {
+	etag := ""
+	if p.Version != "" {
+		tv, err := rd.engine.TemplateVersion(p.Name)
+		if err != nil {
+			return err
+		}
+		etag = ETag(tv, p.Version)
+		w.Header().Set("ETag", etag)
+		if r != nil && matchETag(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	cache := rd.pageCache()
+	if p.CacheKey != "" && cache != nil {
+		if s, ok := cache.Get(p.CacheKey); ok {
+			if i := strings.IndexByte(s, '\n'); i >= 0 && s[:i] == etag {
+				writeHTML(w, s[i+1:])
+				return nil
+			}
+		}
+	}
+
+	rs, err := rd.engine.ParseFile(p.Name, p.Data)
+	if err != nil {
+		return err
+	}
+	if p.CacheKey != "" && cache != nil {
+		cache.Set(p.CacheKey, etag+"\n"+rs, p.TTL)
+	}
+	writeHTML(w, rs)
+	return nil
+}
+
+func writeHTML(w http.ResponseWriter, s string) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", ContentTypeHTML)
+	}
+	io.WriteString(w, s)
+}
+
+// 根据模板版本和数据版本生成ETag
+func ETag(templateVersion, dataVersion string) string {
+	h := sha1.New()
+	h.Write([]byte(templateVersion))
+	h.Write([]byte{0})
+	h.Write([]byte(dataVersion))
+	return fmt.Sprintf("\"%x\"", h.Sum(nil)[:12])
+}
+
+// If-None-Match是否匹配，支持多个ETag、弱ETag和*
+func matchETag(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
+
+var defaultRenderer = New(nil)
+
+// 获取使用默认引擎的HTTP渲染器
+func Default() *Renderer {
+	return defaultRenderer
+}
+
+// 使用默认引擎渲染模板并写入响应
+func Render(w http.ResponseWriter, r *http.Request, name string, data gkt.D) error {
+	return defaultRenderer.Render(w, r, name, data)
+}
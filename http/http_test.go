@@ -0,0 +1,193 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// HTTP渲染单元测试
+package gkhttp
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+func newTestRenderer() (*Renderer, *int) {
+	e := gkt.New()
+	n := 0
+	e.RegisterLib("counter", func(tag *gkt.GKTag, data *gkt.D) string {
+		n++
+		return strconv.Itoa(n)
+	})
+	e.SetLoader(gkt.LoaderFunc(func(name string) (string, error) {
+		return `<h1><{gk:field name="title"/}></h1><{gk:counter/}>`, nil
+	}))
+	return New(e), &n
+}
+
+func TestRenderPageETag(t *testing.T) {
+	rd, n := newTestRenderer()
+	page := Page{Name: "article.htm", Data: gkt.D{"title": "a"}, Version: "v1"}
+
+	w := httptest.NewRecorder()
+	if err := rd.RenderPage(w, httptest.NewRequest("GET", "/", nil), page); err != nil {
+		t.Fatal(err)
+	}
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || w.Body.String() != "<h1>a</h1>1" || w.Header().Get("Content-Type") != ContentTypeHTML {
+		t.Errorf("response code=%d etag=%q body=%q", w.Code, etag, w.Body.String())
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", `"x", W/`+etag)
+	w = httptest.NewRecorder()
+	rd.RenderPage(w, r, page)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || *n != 1 {
+		t.Errorf("conditional response code=%d body=%q renders=%d", w.Code, w.Body.String(), *n)
+	}
+
+	// 数据版本变化后ETag改变
+	page.Version = "v2"
+	w = httptest.NewRecorder()
+	rd.RenderPage(w, r, page)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("new version response code=%d etag=%q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestRenderPageCache(t *testing.T) {
+	rd, n := newTestRenderer()
+	page := Page{Name: "article.htm", Data: gkt.D{"title": "a"}, Version: "v1", CacheKey: "article:1"}
+	render := func() string {
+		w := httptest.NewRecorder()
+		if err := rd.RenderPage(w, httptest.NewRequest("GET", "/", nil), page); err != nil {
+			t.Fatal(err)
+		}
+		return w.Body.String()
+	}
+	if rs := render() + render(); rs != "<h1>a</h1>1<h1>a</h1>1" {
+		t.Errorf("cached result=%q", rs)
+	}
+	page.Version = "v2"
+	if rs := render(); rs != "<h1>a</h1>2" || *n != 2 {
+		t.Errorf("new version result=%q", rs)
+	}
+	rd.InvalidatePrefix("article:")
+	if rs := render(); rs != "<h1>a</h1>3" {
+		t.Errorf("invalidated result=%q", rs)
+	}
+}
+
+func newFileRenderer(files map[string]string) *Renderer {
+	e := gkt.New()
+	e.SetLoader(gkt.LoaderFunc(func(name string) (string, error) {
+		s, ok := files[name]
+		if !ok {
+			return "", os.ErrNotExist
+		}
+		return s, nil
+	}))
+	return New(e)
+}
+
+func TestRender(t *testing.T) {
+	rd := newFileRenderer(map[string]string{
+		"index.htm": `<h1><{gk:field name="title"/}></h1>`,
+		"bad.htm":   `<{gk:call name="missing"/}>`,
+		"error.htm": `<p><{gk:field name="status"/}> <{gk:field name="message"/}></p>`,
+	})
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	w := httptest.NewRecorder()
+	if err := rd.Render(w, httptest.NewRequest("GET", "/", nil), "index.htm", gkt.D{"title": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || w.Body.String() != "<h1>a</h1>" || w.Header().Get("Content-Type") != ContentTypeHTML {
+		t.Errorf("response code=%d body=%q type=%q", w.Code, w.Body.String(), w.Header().Get("Content-Type"))
+	}
+
+	// 错误信息不输出给客户端
+	w = httptest.NewRecorder()
+	if err := rd.Render(w, nil, "bad.htm", nil); err == nil {
+		t.Error("expected render error")
+	}
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "missing") {
+		t.Errorf("error response code=%d body=%q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	rd.Render(w, nil, "none.htm", nil)
+	if w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "none.htm") {
+		t.Errorf("not found response code=%d body=%q", w.Code, w.Body.String())
+	}
+
+	// 错误模板
+	rd.SetErrorTemplate("error.htm")
+	w = httptest.NewRecorder()
+	rd.Render(w, nil, "bad.htm", nil)
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "<p>500 Internal Server Error</p>" || w.Header().Get("Content-Type") != ContentTypeHTML {
+		t.Errorf("error page code=%d body=%q", w.Code, w.Body.String())
+	}
+
+	// 错误模板渲染失败时输出纯文本
+	rd.SetErrorTemplate("bad.htm")
+	w = httptest.NewRecorder()
+	rd.Render(w, nil, "none.htm", nil)
+	if w.Code != http.StatusNotFound || w.Body.String() != "Not Found\n" {
+		t.Errorf("fallback error page code=%d body=%q", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rd := newFileRenderer(map[string]string{
+		"index.htm": `index`,
+		"about.htm": `about <{gk:field name="path"/}>`,
+	})
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	h := rd.Handler(map[string]string{"/": "index.htm", "/about": "about.htm", "/gone": "none.htm"}, nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "index" || etag == "" {
+		t.Errorf("index code=%d body=%q etag=%q", w.Code, w.Body.String(), etag)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional index code=%d", w.Code)
+	}
+
+	for _, c := range []struct {
+		method, path string
+		code         int
+	}{
+		{"GET", "/missing", http.StatusNotFound},
+		{"GET", "/gone", http.StatusNotFound},
+		{"POST", "/", http.StatusMethodNotAllowed},
+	} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+		if w.Code != c.code || w.Header().Get("ETag") != "" {
+			t.Errorf("%s %s code=%d etag=%q, want %d", c.method, c.path, w.Code, w.Header().Get("ETag"), c.code)
+		}
+	}
+
+	// 数据函数
+	h = rd.Handler(map[string]string{"/about": "about.htm"}, func(r *http.Request) gkt.D {
+		return gkt.D{"path": r.URL.Path}
+	})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/about", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "about /about" || w.Header().Get("ETag") != "" {
+		t.Errorf("about code=%d body=%q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 多语言消息目录
+package gktemplate
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 渲染数据中指定语言的键，例：D{"locale": "en"}
+const LocaleKey = "locale"
+
+// Message 一条消息的译文，键为复数类别，没有复数形式的消息只有other
+type Message map[string]string
+
+// Messages 消息目录，键为消息的key
+type Messages map[string]Message
+
+// 下面定义一个存储结构体，保存各语言的消息目录
+type catalogStorage struct {
+	Items map[string]Messages // 存储结构，键为语言
+	sync.RWMutex
+}
+
+func (cs *catalogStorage) Add(locale string, msgs Messages) {
+	cs.Lock()
+	defer cs.Unlock()
+	m := cs.Items[locale]
+	if m == nil {
+		m = make(Messages, len(msgs))
+		cs.Items[locale] = m
+	}
+	for k, v := range msgs {
+		m[k] = v
+	}
+}
+
+func (cs *catalogStorage) Get(locale, key string) (Message, bool) {
+	cs.RLock()
+	defer cs.RUnlock()
+	m, ok := cs.Items[locale][key]
+	return m, ok
+}
+
+func (cs *catalogStorage) Locales() []string {
+	cs.RLock()
+	defer cs.RUnlock()
+	locales := make([]string, 0, len(cs.Items))
+	for l := range cs.Items {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// 规范语言名称，例：zh_CN转换为zh-CN
+func normalizeLocale(locale string) string {
+	return strings.Replace(strings.TrimSpace(locale), "_", "-", -1)
+}
+
+// 添加语言的消息，同名消息被覆盖
+func (e *Engine) AddMessages(locale string, msgs Messages) {
+	e.catalogs.Add(normalizeLocale(locale), msgs)
+}
+
+// 通过加载器读取消息目录，.po文件按PO格式解析，其他文件按JSON格式解析
+// 例：LoadCatalog("en", "i18n/en.json")
+func (e *Engine) LoadCatalog(locale, name string) error {
+	src, err := e.Loader().Load(name)
+	if err != nil {
+		return err
+	}
+	var msgs Messages
+	if strings.ToLower(path.Ext(name)) == ".po" {
+		msgs, err = ParsePO(locale, src)
+	} else {
+		msgs, err = ParseJSONCatalog(src)
+	}
+	if err != nil {
+		return fmt.Errorf("[GKTemplate]catalog %s: %s", name, err)
+	}
+	e.AddMessages(locale, msgs)
+	return nil
+}
+
+// 列出已加载消息的语言
+func (e *Engine) Locales() []string {
+	return e.catalogs.Locales()
+}
+
+// 设置默认语言，渲染数据中没有指定语言或消息不存在时使用
+func (e *Engine) SetDefaultLocale(locale string) {
+	e.Lock()
+	defer e.Unlock()
+	e.locale = normalizeLocale(locale)
+}
+
+// 获取默认语言
+func (e *Engine) DefaultLocale() string {
+	e.RLock()
+	defer e.RUnlock()
+	return e.locale
+}
+
+// 查找消息，依次查找语言（例：zh-CN）、语言的主标签（例：zh）和默认语言
+func (e *Engine) lookupMessage(locale, key string) (Message, string, bool) {
+	locale = normalizeLocale(locale)
+	if m, ok := e.catalogs.Get(locale, key); ok {
+		return m, locale, true
+	}
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		if m, ok := e.catalogs.Get(locale[:i], key); ok {
+			return m, locale[:i], true
+		}
+	}
+	if def := e.DefaultLocale(); def != "" && def != locale {
+		if m, ok := e.catalogs.Get(def, key); ok {
+			return m, def, true
+		}
+	}
+	return nil, locale, false
+}
+
+// 翻译消息，count不为nil时按数量选择复数形式，消息中的{name}替换为args中的值
+// 消息不存在时返回key
+func (e *Engine) Translate(locale, key string, count interface{}, args D) string {
+	m, l, ok := e.lookupMessage(locale, key)
+	if !ok {
+		return key
+	}
+	return interpolateMessage(m.pick(l, count), args)
+}
+
+// 按数量选择复数形式，没有对应类别时使用other
+func (m Message) pick(locale string, count interface{}) string {
+	if count != nil && len(m) > 1 {
+		n, _ := strconv.ParseFloat(strings.TrimSpace(toString(count)), 64)
+		if s, ok := m[PluralCategory(locale, n)]; ok {
+			return s
+		}
+	}
+	if s, ok := m[PluralOther]; ok {
+		return s
+	}
+	for _, c := range []string{PluralOne, PluralFew, PluralMany, PluralTwo, PluralZero} {
+		if s, ok := m[c]; ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// 替换消息中的{name}占位符，不存在的名称保持原样
+func interpolateMessage(s string, args D) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			break
+		}
+		name := s[i+1 : i+j]
+		v, ok := args[name]
+		if !ok {
+			v, ok = args[strings.ToLower(name)]
+		}
+		sb.WriteString(s[:i])
+		if ok {
+			sb.WriteString(toString(v))
+		} else {
+			sb.WriteString(s[i : i+j+1])
+		}
+		s = s[i+j+1:]
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
+
+// 解析JSON格式的消息目录，值为字符串或复数类别到译文的对象
+// 例：{"welcome": "欢迎，{name}", "items": {"one": "{count} item", "other": "{count} items"}}
+// 与PO格式相同，未翻译（译文为空）的消息被忽略
+func ParseJSONCatalog(src string) (Messages, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(src), &raw); err != nil {
+		return nil, err
+	}
+	msgs := make(Messages, len(raw))
+	for k, v := range raw {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			if s != "" {
+				msgs[k] = Message{PluralOther: s}
+			}
+			continue
+		}
+		var m Message
+		if err := json.Unmarshal(v, &m); err != nil {
+			return nil, fmt.Errorf("message '%s' must be a string or an object of plural categories", k)
+		}
+		for c, s := range m {
+			if s == "" {
+				delete(m, c)
+			}
+		}
+		if len(m) > 0 {
+			msgs[k] = m
+		}
+	}
+	return msgs, nil
+}
+
+// 解析PO格式的消息目录，msgstr[n]按头部的Plural-Forms对应复数类别，没有头部时使用语言默认的Plural-Forms；
+// Plural-Forms中没有对应的类别（例：俄语小数使用的other）使用最后一个msgstr
+// 未翻译（msgstr为空）的消息以及msgctxt被忽略
+func ParsePO(locale, src string) (Messages, error) {
+	rule := lookupPlural(locale)
+	index, _, err := pluralIndex(rule, rule.forms)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make(Messages)
+
+	var (
+		id, plural string
+		strs       []string
+		hasID      bool
+		field      = -3 // 续行追加的字段：-1为msgid，-2为msgid_plural，n>=0为msgstr[n]
+	)
+	flush := func() error {
+		defer func() { id, plural, strs, hasID = "", "", nil, false }()
+		switch {
+		case !hasID || len(strs) == 0:
+		case id == "":
+			// 头部信息，只读取Plural-Forms
+			for _, h := range strings.Split(strs[0], "\n") {
+				if h = strings.TrimSpace(h); strings.HasPrefix(h, "Plural-Forms:") {
+					if index, _, err = pluralIndex(rule, strings.TrimSpace(h[13:])); err != nil {
+						return err
+					}
+				}
+			}
+		case plural == "":
+			if strs[0] != "" {
+				msgs[id] = Message{PluralOther: strs[0]}
+			}
+		default:
+			m := make(Message)
+			for c, n := range index {
+				if n < len(strs) && strs[n] != "" {
+					m[c] = strs[n]
+				}
+			}
+			if len(m) > 0 {
+				msgs[id] = m
+			}
+		}
+		return nil
+	}
+	setStr := func(n int, v string, add bool) {
+		for len(strs) <= n {
+			strs = append(strs, "")
+		}
+		if add {
+			v = strs[n] + v
+		}
+		strs[n] = v
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(src))
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || s[0] == '#' {
+			continue
+		}
+		kw, rest := "", s
+		if s[0] != '"' {
+			kw, rest = s, ""
+			if i := strings.IndexAny(s, " \t"); i > 0 {
+				kw, rest = s[:i], strings.TrimSpace(s[i:])
+			}
+		}
+		v, err := strconv.Unquote(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid string %s", line, rest)
+		}
+
+		switch {
+		case kw == "":
+			switch {
+			case field == -1:
+				id += v
+			case field == -2:
+				plural += v
+			case field >= 0:
+				setStr(field, v, true)
+			default:
+				return nil, fmt.Errorf("line %d: unexpected string", line)
+			}
+		case kw == "msgctxt":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			field = -3
+		case kw == "msgid":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			id, hasID, field = v, true, -1
+		case kw == "msgid_plural":
+			plural, field = v, -2
+		case kw == "msgstr":
+			setStr(0, v, false)
+			field = 0
+		case strings.HasPrefix(kw, "msgstr[") && strings.HasSuffix(kw, "]"):
+			n, err := strconv.Atoi(kw[7 : len(kw)-1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("line %d: invalid keyword %s", line, kw)
+			}
+			setStr(n, v, false)
+			field = n
+		default:
+			return nil, fmt.Errorf("line %d: unknown keyword %s", line, kw)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// 添加默认引擎的语言消息
+func AddMessages(locale string, msgs Messages) {
+	defaultEngine.AddMessages(locale, msgs)
+}
+
+// 通过默认引擎的加载器读取消息目录
+func LoadCatalog(locale, name string) error {
+	return defaultEngine.LoadCatalog(locale, name)
+}
+
+// 设置默认引擎的默认语言
+func SetDefaultLocale(locale string) {
+	defaultEngine.SetDefaultLocale(locale)
+}
+
+// 使用默认引擎翻译消息
+func Translate(locale, key string, count interface{}, args D) string {
+	return defaultEngine.Translate(locale, key, count, args)
+}
@@ -0,0 +1,219 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 多语言单元测试
+package gktemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPluralCategory(t *testing.T) {
+	cases := []struct {
+		locale string
+		n      float64
+		want   string
+	}{
+		{"zh", 1, PluralOther},
+		{"en", 1, PluralOne},
+		{"en", 0, PluralOther},
+		{"en", 1.5, PluralOther},
+		{"en-US", 1, PluralOne},
+		{"fr", 0, PluralOne},
+		{"ru", 1, PluralOne},
+		{"ru", 11, PluralMany},
+		{"ru", 22, PluralFew},
+		{"ru", 25, PluralMany},
+		{"ru", 1.5, PluralOther},
+		{"pl", 21, PluralMany},
+		{"pl", 23, PluralFew},
+		{"cs", 3, PluralFew},
+		{"cs", 5, PluralOther},
+		{"cs", 1.5, PluralMany},
+		{"ar", 2, PluralTwo},
+		{"ar", 105, PluralFew},
+		{"xx", 1, PluralOther},
+	}
+	for _, c := range cases {
+		if got := PluralCategory(c.locale, c.n); got != c.want {
+			t.Errorf("PluralCategory(%s, %v)=%s, want %s", c.locale, c.n, got, c.want)
+		}
+	}
+}
+
+func TestParsePO(t *testing.T) {
+	e := New()
+	if err := e.LoadCatalog("ru", "testdata/i18n/ru.po"); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"welcome":      "Добро пожаловать, {name}",
+		"items":        "{count} статья|{count} статьи|{count} статей",
+		"untranslated": "",
+	}
+	for key, want := range cases {
+		m, _, _ := e.lookupMessage("ru", key)
+		got := m[PluralOther]
+		if len(m) > 1 {
+			got = m[PluralOne] + "|" + m[PluralFew] + "|" + m[PluralMany]
+		}
+		if got != want {
+			t.Errorf("message %s=%q, want %q", key, got, want)
+		}
+	}
+
+	// msgstr[n]按Plural-Forms对应，Plural-Forms中没有的类别使用最后一个msgstr
+	if err := e.LoadCatalog("cs", "testdata/i18n/cs.po"); err != nil {
+		t.Fatal(err)
+	}
+	plurals := []struct {
+		locale string
+		count  string
+		want   string
+	}{
+		{"cs", "1", "1 článek"},
+		{"cs", "3", "3 články"},
+		{"cs", "5", "5 článků"},
+		{"cs", "1.5", "1.5 článků"},
+		{"ru", "1", "1 статья"},
+		{"ru", "1.5", "1.5 статей"},
+		{"ru", "21", "21 статья"},
+	}
+	for _, c := range plurals {
+		rs, err := e.ParseString(`<{gk:t key="items" count="`+c.count+`"/}>`, D{LocaleKey: c.locale})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rs != c.want {
+			t.Errorf("%s count=%s result=%q, want %q", c.locale, c.count, rs, c.want)
+		}
+	}
+
+	// 没有Plural-Forms头部时使用语言默认的Plural-Forms
+	msgs, err := ParsePO("cs", "msgid \"a\"\nmsgid_plural \"a\"\nmsgstr[0] \"x\"\nmsgstr[1] \"y\"\nmsgstr[2] \"z\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := msgs["a"]; m[PluralOne] != "x" || m[PluralFew] != "y" || m[PluralMany] != "z" || m[PluralOther] != "z" {
+		t.Errorf("message=%v", m)
+	}
+
+	for _, src := range []string{
+		`msgid "a`,
+		`msgstr "b"` + "\n" + `msgfoo "c"`,
+		`"orphan"`,
+		`msgid ""` + "\n" + `msgstr "Plural-Forms: nplurals=2; plural=(n != 1;\n"`,
+	} {
+		if _, err := ParsePO("en", src); err == nil {
+			t.Errorf("%q: expected error", src)
+		}
+	}
+}
+
+// 未翻译的消息和复数类别被忽略
+func TestPluralForms(t *testing.T) {
+	for _, locale := range []string{"zh", "en", "fr", "ru", "uk", "pl", "cs", "sk", "ar"} {
+		forms, categories := PluralForms(locale)
+		nplurals, plural, err := parsePluralForms(forms)
+		if err != nil || nplurals != len(categories) {
+			t.Fatalf("%s: forms=%q categories=%v err=%v", locale, forms, categories, err)
+		}
+		// 整数的复数类别与Plural-Forms计算的msgstr[n]一致
+		index, _, _ := pluralIndex(lookupPlural(locale), forms)
+		for n := int64(0); n <= 200; n++ {
+			if c := PluralCategory(locale, float64(n)); index[c] != int(plural(n)) {
+				t.Errorf("%s n=%d: category %s index %d, plural=%d", locale, n, c, index[c], plural(n))
+			}
+		}
+	}
+	if forms, categories := PluralForms("ru"); !strings.HasPrefix(forms, "nplurals=3;") || strings.Join(categories, ",") != "one,few,many" {
+		t.Errorf("ru forms=%q categories=%v", forms, categories)
+	}
+}
+
+func TestParseJSONCatalog(t *testing.T) {
+	msgs, err := ParseJSONCatalog(`{"welcome": "Welcome", "empty": "", "items": {"one": "", "other": "{count} items"}, "none": {"one": "", "other": ""}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs["welcome"][PluralOther] != "Welcome" || len(msgs["items"]) != 1 || msgs["items"][PluralOther] != "{count} items" {
+		t.Errorf("catalog=%v", msgs)
+	}
+	if _, err := ParseJSONCatalog(`{"a": 1}`); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestTagT(t *testing.T) {
+	e := New()
+	if err := e.LoadCatalog("en", "testdata/i18n/en.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadCatalog("ru_RU", "testdata/i18n/ru.po"); err != nil {
+		t.Fatal(err)
+	}
+	e.AddMessages("zh", Messages{
+		"welcome": {PluralOther: "欢迎，{name}"},
+		"items":   {PluralOther: "{count}篇文章"},
+	})
+	e.SetDefaultLocale("zh")
+
+	data := D{
+		"user": D{"name": "GoKeep"},
+		"list": []D{{"n": 1}, {"n": 3}},
+	}
+	cases := []struct {
+		locale string
+		want   string
+	}{
+		{"en", "<h1>Welcome, GoKeep</h1>\n1 article3 articles\n<p>Home</p>\n"},
+		{"en-GB", "<h1>Welcome, GoKeep</h1>\n1 article3 articles\n<p>Home</p>\n"},
+		{"ru-RU", "<h1>Добро пожаловать, GoKeep</h1>\n1 статья3 статьи\n<p>home</p>\n"},
+		{"", "<h1>欢迎，GoKeep</h1>\n1篇文章3篇文章\n<p>home</p>\n"},
+	}
+	for _, c := range cases {
+		d := D{LocaleKey: c.locale}
+		for k, v := range data {
+			d[k] = v
+		}
+		rs, err := e.ParseFile("testdata/i18n/index.htm", d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rs != c.want {
+			t.Errorf("locale %q result=%q, want %q", c.locale, rs, c.want)
+		}
+	}
+
+	// locale属性优先，未知占位符保持原样
+	rs, _ := e.ParseString(`<{gk:t key="welcome" locale="en"/}>|<{gk:t key="items" count="21" locale="ru-RU"/}>`, D{LocaleKey: "zh"})
+	if rs != "Welcome, {name}|21 статья" {
+		t.Errorf("locale attribute result=%q", rs)
+	}
+	if diags := e.Validate("", `<{gk:t name="x"/}>`); !HasError(diags) {
+		t.Error("expected missing key error")
+	}
+}
+
+func TestExtractMessages(t *testing.T) {
+	e := New()
+	src := `<{gk:t key="welcome"/}>
+<{gk:range name="list"}><{gk:t key="items" count="{n}"/}><{/gk:range}>
+<{gk:field name="t"/}>`
+	refs, diags := e.ExtractMessages("index.htm", src)
+	if HasError(diags) {
+		t.Errorf("diagnostics: %v", diags)
+	}
+	var got []string
+	for _, r := range refs {
+		s := r.Key
+		if r.Plural {
+			s += "(plural)"
+		}
+		got = append(got, s)
+	}
+	if strings.Join(got, ",") != "welcome,items(plural)" || refs[1].Line != 2 || refs[1].Col != 25 {
+		t.Errorf("refs=%+v", refs)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// cache标签函数
+package gktemplate
+
+import (
+	"time"
+)
+
+var schemaCache = &TagSchema{
+	Kind: TagBlock,
+	Attrs: []AttrSchema{
+		{Name: "key", Required: true},
+		{Name: "ttl"},
+	},
+}
+
+// 解析cache标签内容，渲染结果保存到引擎的片段缓存中，缓存有效期内直接输出
+// 例：<{gk:cache key="nav" ttl="60s"}>...<{/gk:cache}>
+// key中的{name}替换为作用域中的值，例：key="hot:{typeid}"；ttl使用Go的时间格式，例：30s、10m、1h，为空表示不过期。
+// 缓存的只有输出内容，内部set标签设置的变量、宏定义不会保存；
+// 渲染语言不为空时缓存键加上`|语言`，不同语言分别缓存，例：nav|en
+func TagCache(tag *GKTag, data *D) string {
+	key := tag.Interpolate(data, tag.GetAttribute("key"))
+	if locale := tag.renderLocale(data); locale != "" {
+		key += "|" + locale
+	}
+	var ttl time.Duration
+	if s := tag.GetAttribute("ttl"); s != "" {
+		var err error
+		if ttl, err = time.ParseDuration(s); err != nil {
+			tag.Fail("cache ttl '%s' is invalid", s)
+			return ""
+		}
+	}
+
+	c := tag.Engine().FragmentCache()
+	if c == nil {
+		return tag.RenderInner(data, nil)
+	}
+	if s, ok := c.Get(key); ok {
+		return s
+	}
+	ctx := tag.context()
+	s := tag.RenderInner(data, nil)
+	if ctx.err == nil {
+		c.Set(key, s, ttl)
+	}
+	return s
+}
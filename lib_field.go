@@ -4,17 +4,26 @@
 // field标签函数
 package gktemplate
 
-// import (
-// 	"fmt"
-// )
+import (
+	"fmt"
+)
 
 // 解析field标签内容
+// name可以使用`.`访问map或结构体字段，例：<{gk:field name="article.title"/}>
+// `global.`开头的名称可以访问全局变量，例：<{gk:field name="global.webname"/}>
 func TagField(tag *GKTag, data *D) string {
 	name := tag.GetAttribute("name")
-	v, ok := (*data)[name]
-	if ok {
-		return v.(string)
-	} else {
+	return toString(tag.Lookup(data, name))
+}
+
+// 将数据转换为字符串输出
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case nil:
 		return ""
+	default:
+		return fmt.Sprint(s)
 	}
 }
@@ -0,0 +1,155 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// gotpl标签函数，与Go模板互相调用
+package gktemplate
+
+import (
+	"bytes"
+	"html/template"
+	"sync"
+)
+
+var schemaGoTpl = &TagSchema{
+	Kind: TagAny,
+	Attrs: []AttrSchema{
+		{Name: "name"},
+		{Name: "data"},
+	},
+}
+
+// 下面定义一个存储结构体，保存gotpl标签解析后的Go模板
+type goTemplateStorage struct {
+	Items map[string]*template.Template // 存储结构，键为模板源码
+	sync.RWMutex
+}
+
+func (gs *goTemplateStorage) Get(src string) *template.Template {
+	gs.RLock()
+	defer gs.RUnlock()
+	return gs.Items[src]
+}
+
+func (gs *goTemplateStorage) Set(src string, t *template.Template) {
+	gs.Lock()
+	defer gs.Unlock()
+	gs.Items[src] = t
+}
+
+func (gs *goTemplateStorage) Clear() {
+	gs.Lock()
+	defer gs.Unlock()
+	gs.Items = make(map[string]*template.Template)
+}
+
+// 设置gotpl标签使用的html/template模板集合，name属性为集合中的模板名称
+func (e *Engine) SetGoTemplate(t *template.Template) {
+	e.Lock()
+	defer e.Unlock()
+	e.goTemplate = t
+}
+
+// 获取gotpl标签使用的html/template模板集合
+func (e *Engine) GoTemplate() *template.Template {
+	e.RLock()
+	defer e.RUnlock()
+	return e.goTemplate
+}
+
+// 在Go模板中渲染gktemplate的函数：
+// gk渲染模板字符串，gkfile通过加载器读取模板渲染，data可以省略，返回的HTML不再转义
+// 例：{{gk `<{gk:field name="title"/}>` .}}、{{gkfile "header.htm" .}}
+// 同样适用于text/template，gotpl标签的内联模板也可以使用这两个函数
+func (e *Engine) GoFuncs() template.FuncMap {
+	return template.FuncMap{
+		"gk": func(src string, data ...interface{}) (template.HTML, error) {
+			rs, err := e.ParseString(src, goData(data))
+			return template.HTML(rs), err
+		},
+		"gkfile": func(name string, data ...interface{}) (template.HTML, error) {
+			rs, err := e.Render(name, goData(data))
+			return template.HTML(rs), err
+		},
+	}
+}
+
+// Go模板传入的数据转换为D，其他类型作为data变量
+func goData(args []interface{}) D {
+	if len(args) == 0 {
+		return nil
+	}
+	switch v := args[0].(type) {
+	case nil:
+		return nil
+	case D:
+		return v
+	case map[string]interface{}:
+		return D(v)
+	default:
+		return D{"data": v}
+	}
+}
+
+// 解析内联的Go模板，解析结果按源码缓存
+func (e *Engine) parseGoTemplate(src string) (*template.Template, error) {
+	if t := e.goTemplates.Get(src); t != nil {
+		return t, nil
+	}
+	t, err := template.New("gotpl").Funcs(e.GoFuncs()).Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	e.goTemplates.Set(src, t)
+	return t, nil
+}
+
+// 解析gotpl标签内容，使用html/template渲染，模板的数据为当前作用域
+// 例：<{gk:gotpl}><h1>{{.title}}</h1><{/gk:gotpl}>，内部文本作为Go模板，不解析其中的标签
+// 设置name属性时渲染SetGoTemplate设置的模板集合中的模板，例：<{gk:gotpl name="header"/}>
+// 设置data属性时使用该值作为模板数据，例：<{gk:gotpl name="card" data="article"/}>
+func TagGoTpl(tag *GKTag, data *D) string {
+	e := tag.Engine()
+	var dot interface{} = tag.ctx.flatten(data)
+	if name := tag.GetAttribute("data"); name != "" {
+		dot = tag.Lookup(data, name)
+	}
+
+	var buf bytes.Buffer
+	if name := tag.GetAttribute("name"); name != "" {
+		t := e.GoTemplate()
+		if t == nil || t.Lookup(name) == nil {
+			tag.Fail("go template '%s' is not defined", name)
+			return ""
+		}
+		if err := t.ExecuteTemplate(&buf, name, dot); err != nil {
+			tag.Fail("go template '%s': %s", name, err)
+			return ""
+		}
+		return buf.String()
+	}
+
+	if tag.InnerPos < 0 {
+		tag.Fail("gotpl requires name attribute or inner template")
+		return ""
+	}
+	t, err := e.parseGoTemplate(tag.GetInnerString())
+	if err != nil {
+		tag.Fail("go template: %s", err)
+		return ""
+	}
+	if err := t.Execute(&buf, dot); err != nil {
+		tag.Fail("go template: %s", err)
+		return ""
+	}
+	return buf.String()
+}
+
+// 设置默认引擎gotpl标签使用的html/template模板集合
+func SetGoTemplate(t *template.Template) {
+	defaultEngine.SetGoTemplate(t)
+}
+
+// 在Go模板中使用默认引擎渲染gktemplate的函数
+func GoFuncs() template.FuncMap {
+	return defaultEngine.GoFuncs()
+}
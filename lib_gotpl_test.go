@@ -0,0 +1,84 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// gotpl标签单元测试
+package gktemplate
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	texttemplate "text/template"
+)
+
+func TestGoTpl(t *testing.T) {
+	e := New()
+	e.SetGoTemplate(template.Must(template.New("").Parse(
+		`{{define "card"}}<a href="/{{.id}}">{{.title}}</a>{{end}}{{define "bad"}}{{index .items 5}}{{end}}`)))
+	data := D{
+		"title":   "<GoKeep>",
+		"article": D{"id": 1, "title": "a&b"},
+		"items":   []int{1, 2},
+	}
+	cases := []struct {
+		tpl  string
+		want string
+	}{
+		{`<{gk:gotpl}><h1>{{.title}}</h1><{/gk:gotpl}>`, `<h1>&lt;GoKeep&gt;</h1>`},
+		{`<{gk:set name="n" value="3"/}><{gk:gotpl}>{{range .items}}{{.}}{{end}}-{{.n}}<{/gk:gotpl}>`, `12-3`},
+		{`<{gk:gotpl name="card" data="article"/}>`, `<a href="/1">a&amp;b</a>`},
+		{`<{gk:range name="items" as="i"}><{gk:gotpl}>[{{.i}}]<{/gk:gotpl}><{/gk:range}>`, `[1][2]`},
+		{`<{gk:gotpl func="ToUpper(@me)"}>{{.article.title}}<{/gk:gotpl}>`, `A&AMP;B`},
+		{`<{gk:gotpl}>{{gk "<{gk:field name='title'/}>" .}}<{/gk:gotpl}>`, `<GoKeep>`},
+	}
+	for _, c := range cases {
+		rs, err := e.ParseString(c.tpl, data)
+		if err != nil {
+			t.Errorf("%s: %s", c.tpl, err)
+			continue
+		}
+		if rs != c.want {
+			t.Errorf("%s: result=%q, want %q", c.tpl, rs, c.want)
+		}
+	}
+
+	for _, tpl := range []string{
+		`<{gk:gotpl name="missing"/}>`,
+		`<{gk:gotpl name="bad"/}>`,
+		`<{gk:gotpl}>{{.title<{/gk:gotpl}>`,
+		`<{gk:gotpl/}>`,
+	} {
+		if _, err := e.ParseString(tpl, data); err == nil {
+			t.Errorf("%s: expected error", tpl)
+		}
+	}
+}
+
+func TestGoFuncs(t *testing.T) {
+	e := New()
+	e.SetLoader(LoaderFunc(func(name string) (string, error) {
+		return `<b><{gk:field name="title"/}></b>`, nil
+	}))
+	tt := template.Must(template.New("page").Funcs(e.GoFuncs()).Parse(
+		`{{gkfile "title.htm" .}}|{{gk "<{gk:field name='data'/}>" .count}}|{{gk "x"}}`))
+	var sb strings.Builder
+	if err := tt.Execute(&sb, map[string]interface{}{"title": "GoKeep", "count": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if rs := sb.String(); rs != "<b>GoKeep</b>|2|x" {
+		t.Errorf("html/template result=%q", rs)
+	}
+
+	// text/template同样可以使用
+	sb.Reset()
+	ttt := texttemplate.Must(texttemplate.New("page").Funcs(texttemplate.FuncMap(e.GoFuncs())).Parse(`{{gkfile "title.htm" .}}`))
+	if err := ttt.Execute(&sb, D{"title": "a"}); err != nil || sb.String() != "<b>a</b>" {
+		t.Errorf("text/template result=%q err=%v", sb.String(), err)
+	}
+
+	// 渲染错误返回给Go模板
+	tt = template.Must(template.New("bad").Funcs(e.GoFuncs()).Parse(`{{gk "<{gk:call name='x'/}>"}}`))
+	if err := tt.Execute(&sb, nil); err == nil {
+		t.Error("expected render error")
+	}
+}
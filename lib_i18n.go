@@ -0,0 +1,83 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// t标签函数
+package gktemplate
+
+import (
+	"sort"
+)
+
+var schemaT = &TagSchema{
+	Kind:     TagSelfClosing,
+	AnyAttrs: true,
+	Attrs: []AttrSchema{
+		{Name: "key", Required: true},
+		{Name: "count"},
+		{Name: "locale"},
+	},
+}
+
+// 解析t标签内容，输出当前语言的译文
+// 例：<{gk:t key="welcome" name="{user.name}"/}>，消息"欢迎，{name}"输出"欢迎，GoKeep"
+// 除key、count、locale以外的属性作为占位符的值，属性值中的{name}替换为作用域中的值；
+// 设置count时按语言的复数规则选择译文，{count}同样可以作为占位符，例：<{gk:t key="items" count="{total}"/}>
+// 语言依次使用locale属性、渲染数据中的locale和引擎的默认语言，消息不存在时输出key
+func TagT(tag *GKTag, data *D) string {
+	key := tag.GetAttribute("key")
+	locale := tag.Interpolate(data, tag.GetAttribute("locale"))
+	if locale == "" {
+		locale = tag.renderLocale(data)
+	}
+
+	args := D{}
+	for an, av := range tag.CAttribute.Items {
+		if an != "key" && an != "locale" && !commonAttrs[an] {
+			args[an] = tag.interpolateValue(data, av)
+		}
+	}
+	return tag.Engine().Translate(locale, key, args["count"], args)
+}
+
+// 渲染语言，使用渲染数据中的locale，不存在则使用引擎的默认语言
+func (gktag *GKTag) renderLocale(data *D) string {
+	if locale := toString(gktag.Lookup(data, LocaleKey)); locale != "" {
+		return locale
+	}
+	return gktag.Engine().DefaultLocale()
+}
+
+// MessageRef 模板中使用的消息
+type MessageRef struct {
+	Key    string // 消息的key
+	Plural bool   // 是否设置了count属性
+	Name   string // 模板名称
+	Line   int    // 行号
+	Col    int    // 列号
+}
+
+// 列出模板中t标签使用的消息（包括块标签内部），按出现顺序排列，同时返回校验结果
+func (e *Engine) ExtractMessages(name, tplstr string) ([]MessageRef, []Diagnostic) {
+	var refs []MessageRef
+	v := e.newValidator(name, tplstr)
+	v.visit = func(pos int, tpl *GKTemplate, tag *GKTag) {
+		if tag.TagName != "t" || tag.NameSpace != tpl.NameSpace {
+			return
+		}
+		key := tag.CAttribute.GetAtt("key")
+		if key == "" {
+			return
+		}
+		line, col := position(v.src, pos)
+		_, plural := tag.CAttribute.Items["count"]
+		refs = append(refs, MessageRef{Key: key, Plural: plural, Name: name, Line: line, Col: col})
+	}
+	v.check(0, len(v.src))
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].Line != refs[j].Line {
+			return refs[i].Line < refs[j].Line
+		}
+		return refs[i].Col < refs[j].Col
+	})
+	return refs, v.diags
+}
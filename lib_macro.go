@@ -0,0 +1,178 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// macro、call、import标签函数
+package gktemplate
+
+import (
+	"strings"
+)
+
+// 宏调用、import嵌套的默认最大深度，可以通过Limits.MaxDepth修改
+const MacroMaxDepth = 64
+
+// 宏调用时块标签内容的变量名称
+const slotName = "slot"
+
+var (
+	schemaMacro = &TagSchema{
+		Kind: TagBlock,
+		Attrs: []AttrSchema{
+			{Name: "name", Required: true},
+			{Name: "params"},
+		},
+	}
+	schemaCall = &TagSchema{
+		Kind:     TagAny,
+		AnyAttrs: true,
+		Attrs: []AttrSchema{
+			{Name: "name", Required: true},
+		},
+	}
+	schemaImport = &TagSchema{
+		Kind: TagSelfClosing,
+		Attrs: []AttrSchema{
+			{Name: "file", Required: true},
+			{Name: "as"},
+		},
+	}
+)
+
+// 宏定义
+type macro struct {
+	tag    *GKTag   // macro标签
+	data   *D       // 定义宏时的作用域
+	params []string // 参数名称
+}
+
+// 注册模板中的宏定义和导入的宏，在渲染模板的标签之前调用，宏可以先调用后定义
+func (ctx *renderContext) define(defs []*GKTag, data *D) {
+	for _, tag := range defs {
+		switch tag.TagName {
+		case "macro":
+			if tag.InnerPos >= 0 {
+				ctx.defineMacro("", tag, data)
+			}
+		case "import":
+			ctx.importMacros(tag, data)
+		}
+	}
+}
+
+func (ctx *renderContext) defineMacro(prefix string, tag *GKTag, data *D) {
+	name := tag.CAttribute.GetAtt("name")
+	if name == "" {
+		return
+	}
+	m := &macro{tag: tag, data: data}
+	for _, p := range strings.Split(tag.CAttribute.GetAtt("params"), ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			m.params = append(m.params, p)
+		}
+	}
+	if ctx.macros == nil {
+		ctx.macros = make(map[string]*macro)
+	}
+	ctx.macros[prefix+name] = m
+}
+
+// 导入其他模板中定义的宏，设置as属性时宏名称为`as.name`
+// 同一次渲染中相同的导入只处理一次，嵌套导入的深度超过Limits.MaxDepth时返回DepthLimitError
+func (ctx *renderContext) importMacros(tag *GKTag, data *D) {
+	file := tag.CAttribute.GetAtt("file")
+	if file == "" {
+		return
+	}
+	current := ""
+	if tag.tpl != nil {
+		current = tag.tpl.Name
+	}
+	name := resolveName(current, file)
+	prefix := ""
+	if as := tag.CAttribute.GetAtt("as"); as != "" {
+		prefix = as + "."
+	}
+	key := prefix + "|" + name
+	if ctx.imports[key] {
+		return
+	}
+	if ctx.imports == nil {
+		ctx.imports = make(map[string]bool)
+	}
+	ctx.imports[key] = true
+	if max := ctx.maxDepth(); ctx.depth >= max {
+		ctx.fail(&DepthLimitError{tag.errorf("import '%s' depth exceeds %d", file, max), max})
+		return
+	}
+
+	tpl, err := ctx.engine.loadTemplate(name)
+	if err != nil {
+		ctx.fail(tag.errorf("import '%s': %s", file, err))
+		return
+	}
+	ctx.depth++
+	for _, t := range ctx.engine.program(tpl).defs {
+		switch {
+		case t.TagName == "macro" && t.InnerPos >= 0:
+			ctx.defineMacro(prefix, t, data)
+		case t.TagName == "import":
+			ctx.importMacros(t, data)
+		}
+	}
+	ctx.depth--
+}
+
+// 解析macro标签内容，宏在渲染前注册，标签本身不输出内容
+// 例：<{gk:macro name="card" params="title,url"}><a href="[field:url/]">[field:title/]</a>[field:slot/]<{/gk:macro}>
+func TagMacro(tag *GKTag, data *D) string {
+	return ""
+}
+
+// 解析import标签内容，导入在渲染前处理，标签本身不输出内容
+// 例：<{gk:import file="./macros.htm" as="ui"/}>，然后使用<{gk:call name="ui.card"/}>调用
+func TagImport(tag *GKTag, data *D) string {
+	return ""
+}
+
+// 解析call标签内容，调用宏
+// 例：<{gk:call name="card" title="{article.title}" url="/news/{article.id}.html"/}>
+// 参数值中的{name}替换为当前作用域的值，整个值为{name}时传递原始数据；
+// 块标签形式的内容在当前作用域渲染后作为slot参数传给宏。
+// 宏在定义时的作用域中渲染，参数覆盖同名变量，调用深度超过Limits.MaxDepth时返回DepthLimitError
+func TagCall(tag *GKTag, data *D) string {
+	ctx := tag.context()
+	name := tag.GetAttribute("name")
+	m, ok := ctx.macros[name]
+	if !ok {
+		tag.Fail("macro '%s' is not defined", name)
+		return ""
+	}
+	if max := ctx.maxDepth(); ctx.depth >= max {
+		ctx.fail(&DepthLimitError{tag.errorf("macro '%s' call depth exceeds %d", name, max), max})
+		return ""
+	}
+
+	scope := D{}
+	if len(m.params) == 0 {
+		for an, av := range tag.CAttribute.Items {
+			if an != "name" && !commonAttrs[an] {
+				scope[an] = tag.interpolateValue(data, av)
+			}
+		}
+	} else {
+		for _, p := range m.params {
+			scope[p] = nil
+			if av, ok := tag.CAttribute.Items[p]; ok {
+				scope[p] = tag.interpolateValue(data, av)
+			}
+		}
+	}
+	if tag.InnerPos >= 0 {
+		scope[slotName] = tag.RenderInner(data, nil)
+	}
+
+	ctx.depth++
+	s := ctx.renderInner(m.tag, m.data, scope)
+	ctx.depth--
+	return s
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// macro、call、import标签单元测试
+package gktemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMacro(t *testing.T) {
+	tpl := `<{gk:call name="link" title="{article.title}" url="/a/{article.id}.html"/}>|` +
+		`<{gk:macro name="link" params="title,url"}><a href="[field:url/]">[field:title/]</a><{gk:field name="site"/}><{/gk:macro}>` +
+		`<{gk:macro name="list" params="items"}><ul><{gk:range name="items"}><li>[field:slot/][field:name/]</li><{/gk:range}></ul><{/gk:macro}>` +
+		`<{gk:call name="list" items="{tags}"}>#<{/gk:call}>`
+	data := D{
+		"site":    "!",
+		"article": D{"id": 1, "title": "Hello"},
+		"tags":    []D{{"name": "go"}, {"name": "tpl"}},
+	}
+	rs, err := ParseString(tpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `<a href="/a/1.html">Hello</a>!|<ul><li>#go</li><li>#tpl</li></ul>`; rs != expect {
+		t.Errorf("macro result=%q, expect %q", rs, expect)
+	}
+}
+
+func TestMacroImport(t *testing.T) {
+	e := New()
+	rs, err := e.ParseFile("testdata/macro/page.htm", D{"list": []D{{"id": 1, "title": "a"}, {"id": 2, "title": "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `<div class="card"><a href="/news/1.html">a</a><span>new</span>!</div>` +
+		`<div class="card"><a href="/news/2.html">b</a><span>new</span>!</div>` + "\n"
+	if rs != expect {
+		t.Errorf("import result=%q, expect %q", rs, expect)
+	}
+
+	e.SetLoader(LoaderFunc(func(name string) (string, error) {
+		return `<{gk:macro name="hi"}>hi [field:who/]<{/gk:macro}>`, nil
+	}))
+	rs, err = e.ParseString(`<{gk:import file="lib.htm"/}><{gk:call name="hi" who="gk"/}>`, nil)
+	if err != nil || rs != "hi gk" {
+		t.Errorf("loader import result=%q, err=%v", rs, err)
+	}
+}
+
+// 设置Root后不能读取Root之外的文件
+func TestFileLoaderRoot(t *testing.T) {
+	e := New()
+	e.SetLoader(FileLoader{Root: "testdata/macro"})
+	rs, err := e.ParseFile("news/../page.htm", D{"list": []D{{"id": 1, "title": "a"}}})
+	if err != nil || !strings.Contains(rs, `<a href="/news/1.html">a</a>`) {
+		t.Errorf("root result=%q, err=%v", rs, err)
+	}
+	for _, name := range []string{"../tpl1.htm", "../../loader.go", "news/../../tpl1.htm"} {
+		if _, err := e.ParseFile(name, nil); err == nil || !strings.Contains(err.Error(), "outside") {
+			t.Errorf("%s: err=%v", name, err)
+		}
+	}
+	if _, err := e.ParseString(`<{gk:import file="../tpl1.htm"/}>`, nil); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("import outside root err=%v", err)
+	}
+}
+
+func TestMacroErrors(t *testing.T) {
+	_, err := ParseString("a\n  <{gk:call name=\"none\"/}>", nil)
+	re, ok := err.(*RenderError)
+	if !ok || re.Line != 2 || re.Col != 3 || re.Tag != "gk:call" {
+		t.Errorf("undefined macro error=%v", err)
+	}
+
+	_, err = ParseString(`<{gk:macro name="loop"}><{gk:call name="loop"/}><{/gk:macro}><{gk:call name="loop"/}>`, nil)
+	if err == nil || !strings.Contains(err.Error(), "call depth exceeds") {
+		t.Errorf("recursion error=%v", err)
+	}
+
+	_, err = ParseString(`<{gk:import file="testdata/macro/none.htm"/}>`, nil)
+	if _, ok := err.(*RenderError); !ok {
+		t.Errorf("import error=%v", err)
+	}
+}
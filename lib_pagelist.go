@@ -0,0 +1,208 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// pagelist标签函数
+package gktemplate
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// 分页链接类型
+const (
+	pageIndex  = "index"  // 首页
+	pagePre    = "pre"    // 上一页
+	pageNo     = "pageno" // 页码
+	pageNext   = "next"   // 下一页
+	pageEnd    = "end"    // 末页
+	pageInfo   = "info"   // 分页信息：共N页M条
+	pageOption = "option" // 下拉跳转
+)
+
+// 分页链接默认文本
+var pageTexts = map[string]string{
+	pageIndex: "首页",
+	pagePre:   "上一页",
+	pageNext:  "下一页",
+	pageEnd:   "末页",
+}
+
+var schemaPageList = &TagSchema{
+	Kind: TagAny,
+	Attrs: []AttrSchema{
+		{Name: "listitem", Default: "index,pre,pageno,next,end"},
+		{Name: "listsize", Type: AttrInt, Default: "5"},
+		{Name: "url", Default: "?page={page}"},
+		{Name: "first"},
+		{Name: "pagekey", Default: "page"},
+		{Name: "sizekey", Default: "pagesize"},
+		{Name: "totalkey", Default: "total"},
+		{Name: "indextext"},
+		{Name: "pretext"},
+		{Name: "nexttext"},
+		{Name: "endtext"},
+		{Name: "thisclass", Default: "thisclass"},
+	},
+}
+
+// 从数据中读取整数
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(strings.TrimSpace(n))
+		return i
+	}
+	return 0
+}
+
+// 解析pagelist标签内容
+// 例：<{gk:pagelist listitem="index,pre,pageno,next,end" listsize="5" url="/news/list_{page}.html"/}>
+// 块标签形式使用内部文本渲染每个链接，可用[field:type/]、[field:page/]、[field:url/]、[field:text/]，
+// 当前页的[field:current/]为thisclass属性值，其他为空
+func TagPageList(tag *GKTag, data *D) string {
+	page := toInt(tag.Lookup(data, tag.GetAttribute("pagekey")))
+	size := toInt(tag.Lookup(data, tag.GetAttribute("sizekey")))
+	total := toInt(tag.Lookup(data, tag.GetAttribute("totalkey")))
+	if size <= 0 {
+		return ""
+	}
+	totalPage := (total + size - 1) / size
+	if totalPage <= 1 {
+		return ""
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > totalPage {
+		page = totalPage
+	}
+
+	links := pageLinks(tag, page, totalPage, total)
+	if tag.InnerPos >= 0 {
+		return renderItems(tag, data, links)
+	}
+
+	var sb strings.Builder
+	for _, link := range links {
+		text := html.EscapeString(link["text"].(string))
+		switch {
+		case link["type"] == pageInfo:
+			sb.WriteString("<span class=\"pageinfo\">" + text + "</span>\n")
+		case link["type"] == pageOption:
+			sb.WriteString("<option value=\"" + html.EscapeString(link["url"].(string)) + "\"")
+			if link["current"] != "" {
+				sb.WriteString(" selected")
+			}
+			sb.WriteString(">" + text + "</option>\n")
+		case link["current"] != "":
+			sb.WriteString("<li class=\"" + html.EscapeString(link["current"].(string)) + "\">" + text + "</li>\n")
+		default:
+			sb.WriteString("<li><a href=\"" + html.EscapeString(link["url"].(string)) + "\">" + text + "</a></li>\n")
+		}
+	}
+	return sb.String()
+}
+
+// 使用标签内部文本渲染每一项数据，内部文本中的[field:name/]替换为数据项的值
+func renderItems(tag *GKTag, data *D, items []D) string {
+	ctx := tag.context()
+	var sb strings.Builder
+	for _, item := range items {
+		if !ctx.iterate(tag) {
+			return ""
+		}
+		sb.WriteString(tag.RenderInner(data, item))
+		if !ctx.checkOutput(tag, sb.Len()) {
+			return ""
+		}
+	}
+	return sb.String()
+}
+
+// 生成分页链接
+func pageLinks(tag *GKTag, page, totalPage, total int) []D {
+	url := tag.GetAttribute("url")
+	first := tag.GetAttribute("first")
+	pageURL := func(p int) string {
+		if p == 1 && first != "" {
+			return first
+		}
+		return strings.Replace(url, "{page}", strconv.Itoa(p), -1)
+	}
+	thisClass := tag.GetAttribute("thisclass")
+	link := func(typ string, p int, text string) D {
+		current := ""
+		if p == page && (typ == pageNo || typ == pageOption) {
+			current = thisClass
+		}
+		return D{
+			"type":    typ,
+			"page":    p,
+			"url":     pageURL(p),
+			"text":    text,
+			"current": current,
+		}
+	}
+	text := func(typ string) string {
+		if t := tag.GetAttribute(typ + "text"); t != "" {
+			return t
+		}
+		return pageTexts[typ]
+	}
+
+	// 页码窗口
+	listSize := toInt(tag.GetAttribute("listsize"))
+	if listSize <= 0 {
+		listSize = 5
+	}
+	start := page - listSize/2
+	if start+listSize-1 > totalPage {
+		start = totalPage - listSize + 1
+	}
+	if start < 1 {
+		start = 1
+	}
+	end := start + listSize - 1
+	if end > totalPage {
+		end = totalPage
+	}
+
+	var links []D
+	for _, item := range strings.Split(tag.GetAttribute("listitem"), ",") {
+		switch typ := strings.TrimSpace(strings.ToLower(item)); typ {
+		case pageIndex:
+			links = append(links, link(typ, 1, text(typ)))
+		case pagePre:
+			if page > 1 {
+				links = append(links, link(typ, page-1, text(typ)))
+			}
+		case pageNext:
+			if page < totalPage {
+				links = append(links, link(typ, page+1, text(typ)))
+			}
+		case pageEnd:
+			links = append(links, link(typ, totalPage, text(typ)))
+		case pageNo:
+			for p := start; p <= end; p++ {
+				links = append(links, link(typ, p, strconv.Itoa(p)))
+			}
+		case pageOption:
+			for p := 1; p <= totalPage; p++ {
+				links = append(links, link(typ, p, strconv.Itoa(p)))
+			}
+		case pageInfo:
+			links = append(links, link(typ, page, "共"+strconv.Itoa(totalPage)+"页"+strconv.Itoa(total)+"条"))
+		}
+	}
+	return links
+}
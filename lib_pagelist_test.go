@@ -0,0 +1,66 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// pagelist标签单元测试
+package gktemplate
+
+import (
+	"testing"
+)
+
+func TestTagPageList(t *testing.T) {
+	data := D{"page": 3, "pagesize": 10, "total": 95}
+	tpl := `<{gk:pagelist listitem="index,pre,pageno,next,end" listsize="3" url="/news/list_{page}.html" first="/news/"/}>`
+	rs, err := ParseString(tpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `<li><a href="/news/">首页</a></li>
+<li><a href="/news/list_2.html">上一页</a></li>
+<li><a href="/news/list_2.html">2</a></li>
+<li class="thisclass">3</li>
+<li><a href="/news/list_4.html">4</a></li>
+<li><a href="/news/list_4.html">下一页</a></li>
+<li><a href="/news/list_10.html">末页</a></li>
+`
+	if rs != expect {
+		t.Errorf("pagelist result=%q", rs)
+	}
+}
+
+func TestTagPageListInner(t *testing.T) {
+	data := D{"p": "1", "size": 2, "count": 5}
+	tpl := `<{gk:pagelist pagekey="p" sizekey="size" totalkey="count" listitem="pageno,next,info" thisclass="on"}>` +
+		`<a class="[field:current/]" href="[field:url/]">[field:text/]</a>` +
+		`<{/gk:pagelist}>`
+	rs, err := ParseString(tpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `<a class="on" href="?page=1">1</a><a class="" href="?page=2">2</a><a class="" href="?page=3">3</a>` +
+		`<a class="" href="?page=2">下一页</a><a class="" href="?page=1">共3页5条</a>`
+	if rs != expect {
+		t.Errorf("pagelist result=%q", rs)
+	}
+
+	// 只有一页时不输出
+	rs, _ = ParseString(tpl, D{"p": 1, "size": 10, "count": 5})
+	if rs != "" {
+		t.Errorf("single page result=%q", rs)
+	}
+}
+
+// 分页数据可以使用点号路径、set变量和全局变量
+func TestTagPageListLookup(t *testing.T) {
+	e := New()
+	e.SetGlobal("pagesize", 10)
+	tpl := `<{gk:set name="total" value="25"/}>` +
+		`<{gk:pagelist pagekey="pager.page" sizekey="global.pagesize" listitem="info"/}>`
+	rs, err := e.ParseString(tpl, D{"pager": D{"page": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs != "<span class=\"pageinfo\">共3页25条</span>\n" {
+		t.Errorf("pagelist result=%q", rs)
+	}
+}
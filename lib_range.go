@@ -1,54 +1,41 @@
 // Copyright 2020 The GoKeep Authors. All rights reserved.
 // license that can be found in the LICENSE file.
 
-// field标签函数
+// range标签函数
 package gktemplate
 
 import (
-	"fmt"
+	"strings"
 )
 
 // 解析range标签内容
+// 例：<{gk:range name="list"}>[field:title/]<{gk:field name="title"/}><{/gk:range}>
+// 每个数据项在独立的作用域中渲染，数据项的字段优先于外层变量；
+// 设置as属性时数据项整体绑定到该名称，例：<{gk:range name="list" as="item"}>[field:item.title/]<{/gk:range}>
 func TagRange(tag *GKTag, data *D) string {
-	innertText := string(tag.GetInnerText())
-
-	gktp, err := parseTemplate(&innertText, "field", "[", "]", "")
-	if err != nil {
-		return ""
-	}
 	name := tag.GetAttribute("name")
-	items, ok := (*data)[name]
-
-	if !ok {
+	items := itemsOf(tag.Lookup(data, name))
+	if len(items) == 0 {
 		return ""
 	}
 
-	var resultString string
-	var nextTagEnd int
-	for _, item := range items.([]D) {
-		nextTagEnd = 0
-		for i := 0; i < gktp.Count; i++ {
-			rs, ok := item[gktp.CTags[i].TagName]
-			if ok {
-				resultString += string(gktp.SourceString[nextTagEnd : nextTagEnd+gktp.CTags[i].StartPos-nextTagEnd])
-
-				switch v := rs.(type) {
-				case string:
-					resultString += v
-				case int:
-					resultString += fmt.Sprintf("%d", v)
-				default:
-					resultString += ""
-				}
-
-				nextTagEnd = gktp.CTags[i].EndPos
-			}
+	as := tag.GetAttribute("as")
+	var sb strings.Builder
+	ctx := tag.context()
+	for _, item := range items {
+		if !ctx.iterate(tag) {
+			return ""
 		}
-		slen := len(gktp.SourceString)
-		if slen > nextTagEnd {
-			resultString += string(gktp.SourceString[nextTagEnd:slen])
+		var scope D
+		if as != "" {
+			scope = D{as: item}
+		} else {
+			scope, _ = fieldsOf(item)
+		}
+		sb.WriteString(tag.RenderInner(data, scope))
+		if !ctx.checkOutput(tag, sb.Len()) {
+			return ""
 		}
 	}
-
-	return resultString
+	return sb.String()
 }
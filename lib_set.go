@@ -0,0 +1,43 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// set标签函数
+package gktemplate
+
+var schemaSet = &TagSchema{
+	Kind: TagAny,
+	Attrs: []AttrSchema{
+		{Name: "name", Required: true},
+		{Name: "value"},
+		{Name: "field"},
+	},
+}
+
+// 解析set标签内容，在当前作用域设置变量，标签本身不输出内容
+// 例：<{gk:set name="title" value="首页"/}>
+// <{gk:set name="author" field="article.author" func="ToUpper(@me)"/}>
+// <{gk:set name="link"}><a href="[field:url/]">[field:title/]</a><{/gk:set}>
+// field属性从作用域中取值并保留原始类型，块标签形式的值为内部文本的渲染结果；
+// 设置func属性时先处理值再保存。在range、with等块标签内部设置的变量只在该块内有效
+func TagSet(tag *GKTag, data *D) string {
+	tag.funcDone = true
+	name := tag.GetAttribute("name")
+	if name == "" {
+		return ""
+	}
+
+	var v interface{}
+	switch {
+	case tag.IsAttribute("field"):
+		v = tag.Lookup(data, tag.GetAttribute("field"))
+	case tag.InnerPos >= 0:
+		v = tag.RenderInner(data, nil)
+	default:
+		v = tag.GetAttribute("value")
+	}
+	if tag.IsAttribute("func") {
+		v = tag.callFunc(toString(v))
+	}
+	(*data)[name] = v
+	return ""
+}
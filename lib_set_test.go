@@ -0,0 +1,87 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// set、with标签及作用域单元测试
+package gktemplate
+
+import (
+	"testing"
+)
+
+type testAuthor struct {
+	Name string
+}
+
+func TestTagSet(t *testing.T) {
+	data := D{
+		"title":   "outer",
+		"article": D{"title": "Hello", "author": &testAuthor{Name: "gokeep"}},
+	}
+	tpl := `<{gk:set name="t" field="article.title"/}>` +
+		`<{gk:set name="au" field="article.author.name" func="ToUpper(@me)"/}>` +
+		`<{gk:set name="link"}><a>[field:t/]</a><{/gk:set}>` +
+		`<{gk:field name="t"/}>|<{gk:field name="au"/}>|<{gk:field name="link"/}>`
+	rs, err := ParseString(tpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "Hello|GOKEEP|<a>Hello</a>"; rs != expect {
+		t.Errorf("set result=%q, expect %q", rs, expect)
+	}
+	if _, ok := data["t"]; ok {
+		t.Errorf("set must not modify caller data")
+	}
+}
+
+func TestScope(t *testing.T) {
+	data := D{
+		"title": "site",
+		"list": []D{
+			{"title": "a", "tags": []string{"x", "y"}},
+			{"title": "b"},
+		},
+	}
+	tpl := `<{gk:range name="list"}>` +
+		`<{gk:set name="n" field="title"/}>` +
+		`[field:title/]:<{gk:range name="tags" as="tag"}>[field:tag/][field:n/]<{/gk:range}>;` +
+		`<{/gk:range}>` +
+		`<{gk:field name="title"/}>[<{gk:field name="n"/}>]`
+	rs, err := ParseString(tpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内部作用域的变量在外部不可见
+	if expect := "a:xaya;b:;site[]"; rs != expect {
+		t.Errorf("scope result=%q, expect %q", rs, expect)
+	}
+}
+
+func TestTagWith(t *testing.T) {
+	data := D{
+		"title":   "site",
+		"article": map[string]interface{}{"title": "Hello", "author": testAuthor{Name: "gokeep"}},
+	}
+	tpl := `<{gk:with name="article"}><{gk:field name="title"/}>` +
+		`<{gk:with name="author" as="au"}>/[field:au.name/]/<{gk:field name="title"/}><{/gk:with}>` +
+		`<{/gk:with}>|<{gk:field name="title"/}>` +
+		`<{gk:with name="missing"}>none<{/gk:with}>`
+	rs, err := ParseString(tpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "Hello/gokeep/Hello|site"; rs != expect {
+		t.Errorf("with result=%q, expect %q", rs, expect)
+	}
+}
+
+func TestInnerParseError(t *testing.T) {
+	tpl := "<{gk:range name=\"list\"}>\n  <{gk:with name=\"x\"}><{/gk:range}>"
+	_, err := ParseString(tpl, D{"list": []D{{}}})
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expect ParseError, got %v", err)
+	}
+	if pe.Line != 2 || pe.Col != 3 {
+		t.Errorf("error position=%d:%d, expect 2:3", pe.Line, pe.Col)
+	}
+}
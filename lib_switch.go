@@ -0,0 +1,84 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// switch、case、default标签函数
+package gktemplate
+
+import (
+	"strings"
+)
+
+var (
+	schemaSwitch = &TagSchema{
+		Kind: TagBlock,
+		Attrs: []AttrSchema{
+			{Name: "name", Required: true},
+		},
+	}
+	schemaCase = &TagSchema{
+		Kind: TagBlock,
+		Attrs: []AttrSchema{
+			{Name: "value", Required: true},
+		},
+	}
+	schemaDefault = &TagSchema{
+		Kind: TagBlock,
+	}
+)
+
+// 解析switch标签内容，按name的值选择第一个匹配的case分支渲染，都不匹配时渲染default分支
+// 例：<{gk:switch name="article.status"}><{gk:case value="draft"}>草稿<{/gk:case}><{gk:case value="a,b"}>...<{/gk:case}><{gk:default}>...<{/gk:default}><{/gk:switch}>
+// 取值规则与field标签相同，case的value用`,`分隔多个值，分支之外的内容不输出
+func TagSwitch(tag *GKTag, data *D) string {
+	tpl, err := tag.parseInner()
+	if err != nil {
+		tag.context().fail(err)
+		return ""
+	}
+	v := toString(tag.Lookup(data, tag.GetAttribute("name")))
+
+	var branch, def *GKTag
+	for i := 0; i < tpl.Count && branch == nil; i++ {
+		t := tpl.CTags[i]
+		if t.isField || t.literal || t.InnerPos < 0 || t.NameSpace != tpl.NameSpace {
+			continue
+		}
+		switch t.GetTagName() {
+		case "case":
+			if caseMatch(t.CAttribute.GetAtt("value"), v) {
+				branch = t
+			}
+		case "default":
+			if def == nil {
+				def = t
+			}
+		}
+	}
+	if branch == nil {
+		branch = def
+	}
+	if branch == nil {
+		return ""
+	}
+
+	// 分支标签来自缓存的模板，使用副本渲染
+	b := *branch
+	b.ctx = tag.ctx
+	return b.RenderInner(data, nil)
+}
+
+// case的值是否匹配，多个值用`,`分隔
+func caseMatch(values, v string) bool {
+	for _, s := range strings.Split(values, ",") {
+		if strings.TrimSpace(s) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// case、default标签只能在switch标签内使用
+func TagCase(tag *GKTag, data *D) string {
+	tag.Fail("tag '%s' must be inside switch", tag.GetTagName())
+	return ""
+}
@@ -0,0 +1,40 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// switch标签单元测试
+package gktemplate
+
+import (
+	"testing"
+)
+
+func TestTagSwitch(t *testing.T) {
+	tpl := `<{gk:range name="list"}><{gk:switch name="status"}>
+<{gk:case value="draft"}>草稿<{/gk:case}>
+<{gk:case value="review, pending"}>[field:title/]待审<{/gk:case}>
+<{gk:default}>已发布<{gk:switch name="top"}><{gk:case value="1"}>[置顶]<{/gk:case}><{/gk:switch}><{/gk:default}>
+<{/gk:switch}>|<{/gk:range}>`
+	data := D{"list": []D{
+		{"status": "draft"},
+		{"status": "pending", "title": "a"},
+		{"status": "online", "top": 1},
+		{},
+	}}
+	rs, err := ParseString(tpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "草稿|a待审|已发布[置顶]|已发布|"; rs != expect {
+		t.Errorf("switch result=%q, expect %q", rs, expect)
+	}
+
+	rs, err = ParseString(`<{gk:switch name="article.type"}><{gk:case value="1"}>one<{/gk:case}><{/gk:switch}>`, D{"article": D{"type": 2}})
+	if err != nil || rs != "" {
+		t.Errorf("switch without default result=%q, err=%v", rs, err)
+	}
+
+	_, err = ParseString("<{gk:case value=\"1\"}>one<{/gk:case}>", nil)
+	if re, ok := err.(*RenderError); !ok || re.Tag != "gk:case" {
+		t.Errorf("case outside switch error=%v", err)
+	}
+}
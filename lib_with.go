@@ -0,0 +1,32 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// with标签函数
+package gktemplate
+
+var schemaWith = &TagSchema{
+	Kind: TagBlock,
+	Attrs: []AttrSchema{
+		{Name: "name", Required: true},
+		{Name: "as"},
+	},
+}
+
+// 解析with标签内容，值不存在或为空时不输出
+// 例：<{gk:with name="article"}><{gk:field name="title"/}><{/gk:with}>
+// map或结构体的字段在新的作用域中可以直接使用，外层同名变量被覆盖；
+// 设置as属性时值整体绑定到该名称，例：<{gk:with name="article.author" as="au"}>[field:au.name/]<{/gk:with}>
+func TagWith(tag *GKTag, data *D) string {
+	v := tag.Lookup(data, tag.GetAttribute("name"))
+	if v == nil || v == "" {
+		return ""
+	}
+	if as := tag.GetAttribute("as"); as != "" {
+		return tag.RenderInner(data, D{as: v})
+	}
+	scope, ok := fieldsOf(v)
+	if !ok {
+		return ""
+	}
+	return tag.RenderInner(data, scope)
+}
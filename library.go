@@ -0,0 +1,166 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 标签库
+package gktemplate
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+// Library 标签库，将一组标签、函数及标签定义打包，可以一次注册到引擎
+// 设置了NameSpace的标签库使用独立的命名空间，例如`<{shop:price/}>`，
+// 可以和默认命名空间`<{gk:field/}>`在同一个模板中使用
+type Library struct {
+	Name      string                // 标签库名称
+	NameSpace string                // 命名空间，为空则注册到引擎默认命名空间
+	Tags      map[string]TagLib     // 标签
+	Funcs     map[string]TagFunc    // 函数
+	Schemas   map[string]*TagSchema // 标签定义，键为标签名称
+}
+
+// 标签在注册表中的名称
+func (lib *Library) tagKey(name string) string {
+	if lib.NameSpace == "" {
+		return name
+	}
+	return lib.NameSpace + ":" + name
+}
+
+// 注册标签库，标签库名称、标签或函数已存在则返回错误，且不做任何注册
+// 传入多个标签库时先全部校验，其中一个失败则都不注册
+func (e *Engine) Use(libs ...*Library) error {
+	e.Lock()
+	defer e.Unlock()
+	seen := &libraryNames{
+		libs:  make(map[string]bool),
+		tags:  make(map[string]bool),
+		funcs: make(map[string]bool),
+	}
+	for _, lib := range libs {
+		if err := e.checkLibrary(lib, seen); err != nil {
+			return err
+		}
+	}
+	for i, lib := range libs {
+		if err := e.useLibrary(lib); err != nil {
+			for _, used := range libs[:i] {
+				e.unuseLibrary(used.Name)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// 本次注册中已出现的名称，用于检查多个标签库之间的冲突
+type libraryNames struct {
+	libs  map[string]bool
+	tags  map[string]bool
+	funcs map[string]bool
+}
+
+// 校验标签库，不修改引擎
+func (e *Engine) checkLibrary(lib *Library, seen *libraryNames) error {
+	if lib.Name == "" {
+		return fmt.Errorf("[GKTemplate]library name is empty")
+	}
+	if _, ok := e.libraries[lib.Name]; ok || seen.libs[lib.Name] {
+		return fmt.Errorf("[GKTemplate]library:%s exists", lib.Name)
+	}
+	if lib.NameSpace != "" && !reNameSpace.MatchString(lib.NameSpace) {
+		return fmt.Errorf("[GKTemplate]library:%s %s", lib.Name, errNameSpaceInvalid)
+	}
+	for name := range lib.Tags {
+		key := lib.tagKey(name)
+		if _, ok := e.libs.Get(key); ok || seen.tags[key] {
+			return fmt.Errorf("[GKTemplate]library:%s tag:%s exists", lib.Name, key)
+		}
+	}
+	for name := range lib.Funcs {
+		if _, ok := e.funcs.Get(name); ok || seen.funcs[name] {
+			return fmt.Errorf("[GKTemplate]library:%s func:%s exists", lib.Name, name)
+		}
+	}
+
+	seen.libs[lib.Name] = true
+	for name := range lib.Tags {
+		seen.tags[lib.tagKey(name)] = true
+	}
+	for name := range lib.Funcs {
+		seen.funcs[name] = true
+	}
+	return nil
+}
+
+func (e *Engine) useLibrary(lib *Library) error {
+	tags := make(map[string]TagLib, len(lib.Tags))
+	for name, tl := range lib.Tags {
+		tags[lib.tagKey(name)] = tl
+	}
+
+	if err := e.libs.AddAll(tags); err != nil {
+		return fmt.Errorf("[GKTemplate]library:%s %s", lib.Name, err)
+	}
+	if err := e.funcs.AddAll(lib.Funcs); err != nil {
+		for name := range tags {
+			e.libs.Delete(name)
+		}
+		return fmt.Errorf("[GKTemplate]library:%s %s", lib.Name, err)
+	}
+	for name, schema := range lib.Schemas {
+		e.schemas[lib.tagKey(name)] = schema
+	}
+	atomic.AddUint64(&e.schemaVersion, 1)
+	e.libraries[lib.Name] = lib
+	if lib.NameSpace != "" {
+		e.templates.Clear()
+	}
+	return nil
+}
+
+// 删除标签库及其注册的标签、函数，返回标签库是否存在
+func (e *Engine) Unuse(name string) bool {
+	e.Lock()
+	defer e.Unlock()
+	return e.unuseLibrary(name)
+}
+
+func (e *Engine) unuseLibrary(name string) bool {
+	lib, ok := e.libraries[name]
+	if !ok {
+		return false
+	}
+	for tn := range lib.Tags {
+		e.libs.Delete(lib.tagKey(tn))
+		delete(e.schemas, lib.tagKey(tn))
+	}
+	atomic.AddUint64(&e.schemaVersion, 1)
+	for fn := range lib.Funcs {
+		e.funcs.Delete(fn)
+	}
+	delete(e.libraries, name)
+	if lib.NameSpace != "" {
+		e.templates.Clear()
+	}
+	return true
+}
+
+// 列出已注册的标签库
+func (e *Engine) Libraries() []string {
+	e.RLock()
+	defer e.RUnlock()
+	names := make([]string, 0, len(e.libraries))
+	for name := range e.libraries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 在默认引擎注册标签库
+func Use(libs ...*Library) error {
+	return defaultEngine.Use(libs...)
+}
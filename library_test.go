@@ -0,0 +1,148 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 标签库单元测试
+package gktemplate
+
+import (
+	"testing"
+)
+
+var shopLib = &Library{
+	Name:      "shop",
+	NameSpace: "shop",
+	Tags: map[string]TagLib{
+		"price": func(tag *GKTag, data *D) string {
+			return "￥" + (*data)[tag.GetAttribute("name")].(string)
+		},
+	},
+	Funcs: map[string]TagFunc{
+		"Yuan": func(v *string, args ...interface{}) string {
+			return *v + "元"
+		},
+	},
+}
+
+func TestEngineUse(t *testing.T) {
+	e := New()
+	if err := e.Use(shopLib); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Use(shopLib); err == nil {
+		t.Errorf("use a library twice should return error")
+	}
+
+	tpl := `<{gk:field name="title"/}>:<{shop:price name="price"/}>,<{gk:field name="price" func="Yuan(@me)"/}>`
+	rs, err := e.ParseString(tpl, D{"title": "GoKeep", "price": "9.9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs != "GoKeep:￥9.9,9.9元" {
+		t.Errorf("Use result=%q", rs)
+	}
+
+	// 默认引擎未注册shop命名空间，原样输出
+	rs, err = ParseString(`<{shop:price name="price"/}>`, D{"price": "9.9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs != `<{shop:price name="price"/}>` {
+		t.Errorf("default engine result=%q", rs)
+	}
+
+	if !e.Unuse("shop") {
+		t.Errorf("Unuse should report existing library")
+	}
+	if len(e.Libraries()) != 0 {
+		t.Errorf("Libraries should be empty")
+	}
+	if _, ok := e.LookupFunc("Yuan"); ok {
+		t.Errorf("library func should be removed")
+	}
+}
+
+func TestEngineUseConflict(t *testing.T) {
+	e := New()
+	lib := &Library{
+		Name: "conflict",
+		Tags: map[string]TagLib{
+			"hello": TagField,
+			"field": TagField,
+		},
+	}
+	if err := e.Use(lib); err == nil {
+		t.Errorf("library overriding field should return error")
+	}
+	if _, ok := e.LookupLib("hello"); ok {
+		t.Errorf("failed library should not register any tag")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := ParseString("Hello\n  <{gk:range name='items'}>", nil)
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expect *ParseError, got %v", err)
+	}
+	if pe.Line != 2 || pe.Col != 3 {
+		t.Errorf("ParseError position=%d:%d", pe.Line, pe.Col)
+	}
+}
+
+// 多个标签库中有一个校验失败时，其它标签库也不注册
+func TestEngineUseAtomic(t *testing.T) {
+	e := New()
+	good := &Library{
+		Name:  "good",
+		Tags:  map[string]TagLib{"hello": TagField},
+		Funcs: map[string]TagFunc{"Hello": FuncToUpper},
+	}
+	bad := &Library{
+		Name: "bad",
+		Tags: map[string]TagLib{"field": TagField},
+	}
+	if err := e.Use(good, bad); err == nil {
+		t.Errorf("library overriding field should return error")
+	}
+	if len(e.Libraries()) != 0 {
+		t.Errorf("Libraries=%v, want none", e.Libraries())
+	}
+	if _, ok := e.LookupLib("hello"); ok {
+		t.Errorf("tag of the valid library should not be registered")
+	}
+	if _, ok := e.LookupFunc("Hello"); ok {
+		t.Errorf("func of the valid library should not be registered")
+	}
+
+	// 同一次注册中的两个标签库之间也不能冲突
+	dup := &Library{Name: "dup", Funcs: map[string]TagFunc{"Hello": FuncToLower}}
+	if err := e.Use(good, dup); err == nil {
+		t.Errorf("conflicting libraries should return error")
+	}
+	if len(e.Libraries()) != 0 {
+		t.Errorf("Libraries=%v, want none", e.Libraries())
+	}
+}
+
+// 命名空间列表缓存，标签库变化后重新生成
+func TestNameSpacesCache(t *testing.T) {
+	e := New()
+	a := e.nameSpaces("gk", "<{", "}>")
+	b := e.nameSpaces("gk", "<{", "}>")
+	if len(a) != 1 || &a[0] != &b[0] {
+		t.Errorf("namespaces should be cached: %v %v", a, b)
+	}
+	if err := e.Use(shopLib); err != nil {
+		t.Fatal(err)
+	}
+	if nss := e.nameSpaces("gk", "<{", "}>"); len(nss) != 2 || nss[1] != "shop" {
+		t.Errorf("namespaces after Use=%v", nss)
+	}
+	if nss := e.nameSpaces("dede", "<{", "}>"); len(nss) != 2 || nss[0] != "dede" {
+		t.Errorf("namespaces with another default=%v", nss)
+	}
+	e.Unuse("shop")
+	if nss := e.nameSpaces("gk", "<{", "}>"); len(nss) != 1 {
+		t.Errorf("namespaces after Unuse=%v", nss)
+	}
+}
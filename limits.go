@@ -0,0 +1,120 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 渲染资源限制
+package gktemplate
+
+import (
+	"time"
+)
+
+// Limits 单次渲染的资源限制，0表示不限制
+// 超过限制时停止渲染，返回对应类型的错误，错误中包含出错标签的位置
+type Limits struct {
+	MaxOutput     int           // 输出的最大字节数
+	MaxIterations int           // range等循环的总次数
+	MaxDepth      int           // 宏调用、import嵌套的最大深度，0表示使用MacroMaxDepth
+	MaxTags       int           // 执行标签的总次数，包括[field:name/]
+	Timeout       time.Duration // 最长渲染时间
+}
+
+// OutputLimitError 输出超过Limits.MaxOutput
+type OutputLimitError struct {
+	*RenderError
+	Max int
+}
+
+// IterationLimitError 循环次数超过Limits.MaxIterations
+type IterationLimitError struct {
+	*RenderError
+	Max int
+}
+
+// DepthLimitError 宏调用或import嵌套深度超过Limits.MaxDepth
+type DepthLimitError struct {
+	*RenderError
+	Max int
+}
+
+// TagLimitError 执行标签次数超过Limits.MaxTags
+type TagLimitError struct {
+	*RenderError
+	Max int
+}
+
+// TimeoutError 渲染时间超过Limits.Timeout
+type TimeoutError struct {
+	*RenderError
+	Timeout time.Duration
+}
+
+// 设置单次渲染的资源限制，模板由非开发人员编辑时用于防止渲染耗尽资源
+func (e *Engine) SetLimits(l Limits) {
+	e.Lock()
+	defer e.Unlock()
+	e.limits = l
+}
+
+// 获取单次渲染的资源限制
+func (e *Engine) Limits() Limits {
+	e.RLock()
+	defer e.RUnlock()
+	return e.limits
+}
+
+// 设置默认引擎的资源限制
+func SetLimits(l Limits) {
+	defaultEngine.SetLimits(l)
+}
+
+// 宏调用、import嵌套的最大深度
+func (ctx *renderContext) maxDepth() int {
+	if ctx.limits.MaxDepth > 0 {
+		return ctx.limits.MaxDepth
+	}
+	return MacroMaxDepth
+}
+
+// 执行标签前调用，检查标签次数和渲染时间，超过限制返回false
+func (ctx *renderContext) evaluate(tag *GKTag) bool {
+	if ctx.err != nil {
+		return false
+	}
+	ctx.tags++
+	if max := ctx.limits.MaxTags; max > 0 && ctx.tags > max {
+		ctx.fail(&TagLimitError{tag.errorf("tag evaluations exceed %d", max), max})
+		return false
+	}
+	return ctx.checkTime(tag)
+}
+
+// 每次循环前调用，检查循环次数和渲染时间，超过限制返回false
+func (ctx *renderContext) iterate(tag *GKTag) bool {
+	if ctx.err != nil {
+		return false
+	}
+	ctx.iterations++
+	if max := ctx.limits.MaxIterations; max > 0 && ctx.iterations > max {
+		ctx.fail(&IterationLimitError{tag.errorf("loop iterations exceed %d", max), max})
+		return false
+	}
+	return ctx.checkTime(tag)
+}
+
+func (ctx *renderContext) checkTime(tag *GKTag) bool {
+	if ctx.deadline.IsZero() || time.Now().Before(ctx.deadline) {
+		return true
+	}
+	ctx.fail(&TimeoutError{tag.errorf("render time exceeds %s", ctx.limits.Timeout), ctx.limits.Timeout})
+	return false
+}
+
+// 检查已生成的输出长度，超过限制返回false
+// 内部模板的输出会合并到外层，每一层的长度都不超过最终输出，所以只需检查当前层
+func (ctx *renderContext) checkOutput(tag *GKTag, n int) bool {
+	if max := ctx.limits.MaxOutput; max > 0 && n > max {
+		ctx.fail(&OutputLimitError{tag.errorf("output exceeds %d bytes", max), max})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 渲染资源限制单元测试
+package gktemplate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	list := make([]D, 100)
+	for i := range list {
+		list[i] = D{"id": i}
+	}
+	data := D{"list": list}
+	loop := "\n <{gk:range name=\"list\"}>[field:id/],<{/gk:range}>"
+
+	e := New()
+	e.SetLimits(Limits{MaxIterations: 10})
+	_, err := e.ParseString(loop, data)
+	if le, ok := err.(*IterationLimitError); !ok || le.Max != 10 || le.Line != 2 || le.Col != 2 {
+		t.Errorf("iteration limit error=%v", err)
+	}
+
+	e.SetLimits(Limits{MaxOutput: 50})
+	_, err = e.ParseString(loop, data)
+	if le, ok := err.(*OutputLimitError); !ok || le.Tag != "gk:range" {
+		t.Errorf("output limit error=%v", err)
+	}
+	_, err = e.ParseString(strings.Repeat("a", 51), nil)
+	if _, ok := err.(*OutputLimitError); !ok {
+		t.Errorf("output limit error=%v", err)
+	}
+
+	e.SetLimits(Limits{MaxTags: 20})
+	_, err = e.ParseString(loop, data)
+	if le, ok := err.(*TagLimitError); !ok || le.Tag != "field:id" {
+		t.Errorf("tag limit error=%v", err)
+	}
+
+	e.SetLimits(Limits{MaxDepth: 3})
+	rs, err := e.ParseString(`<{gk:macro name="a"}>a<{gk:call name="b"/}><{/gk:macro}><{gk:macro name="b"}>b<{/gk:macro}><{gk:call name="a"/}>`, nil)
+	if err != nil || rs != "ab" {
+		t.Errorf("depth result=%q, err=%v", rs, err)
+	}
+	_, err = e.ParseString(`<{gk:macro name="loop"}><{gk:call name="loop"/}><{/gk:macro}><{gk:call name="loop"/}>`, nil)
+	if le, ok := err.(*DepthLimitError); !ok || le.Max != 3 {
+		t.Errorf("depth limit error=%v", err)
+	}
+
+	// import嵌套同样受MaxDepth限制
+	e.SetLoader(LoaderFunc(func(name string) (string, error) {
+		n := int(name[1] - '0')
+		if n == 6 {
+			return `<{gk:macro name="m6"}>6<{/gk:macro}>`, nil
+		}
+		return fmt.Sprintf(`<{gk:import file="t%d.htm"/}><{gk:macro name="m%d"}>%d<{/gk:macro}>`, n+1, n, n), nil
+	}))
+	_, err = e.ParseString(`<{gk:import file="t0.htm"/}><{gk:call name="m0"/}>`, nil)
+	if le, ok := err.(*DepthLimitError); !ok || le.Max != 3 || le.Name != "t2.htm" {
+		t.Errorf("import depth limit error=%v", err)
+	}
+	rs, err = e.ParseString(`<{gk:import file="t4.htm"/}><{gk:call name="m6"/}>`, nil)
+	if err != nil || rs != "6" {
+		t.Errorf("import depth result=%q, err=%v", rs, err)
+	}
+
+	e.SetLimits(Limits{Timeout: time.Millisecond})
+	e.ExtLibs(map[string]TagLib{"sleep": func(tag *GKTag, data *D) string {
+		time.Sleep(2 * time.Millisecond)
+		return ""
+	}})
+	_, err = e.ParseString(`<{gk:sleep/}><{gk:field name="a"/}>`, nil)
+	if le, ok := err.(*TimeoutError); !ok || le.Tag != "gk:field" {
+		t.Errorf("timeout error=%v", err)
+	}
+
+	e.SetLimits(Limits{})
+	rs, err = e.ParseString(loop, data)
+	if err != nil || strings.Count(rs, ",") != 100 {
+		t.Errorf("unlimited result=%q, err=%v", rs, err)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板加载器
+package gktemplate
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Loader 模板加载器，根据名称读取模板内容
+// ParseFile、import标签等通过引擎的加载器读取模板
+type Loader interface {
+	Load(name string) (string, error)
+}
+
+// Errors
+var errOutsideRoot = errors.New("template is outside of loader root")
+
+// FileLoader 从文件系统读取模板，Root不为空时名称相对于Root，
+// 通过../等方式指向Root之外的名称返回错误
+type FileLoader struct {
+	Root string
+}
+
+func (fl FileLoader) Load(name string) (string, error) {
+	filename := filepath.FromSlash(name)
+	if fl.Root != "" {
+		root := filepath.Clean(fl.Root)
+		filename = filepath.Join(root, filename)
+		rel, err := filepath.Rel(root, filename)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", &os.PathError{Op: "load", Path: name, Err: errOutsideRoot}
+		}
+	}
+	d, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return string(d), nil
+}
+
+// LoaderFunc 函数形式的加载器
+type LoaderFunc func(name string) (string, error)
+
+func (f LoaderFunc) Load(name string) (string, error) {
+	return f(name)
+}
+
+// 设置模板加载器，修改后清空模板缓存
+func (e *Engine) SetLoader(l Loader) {
+	e.Lock()
+	e.loader = l
+	e.Unlock()
+	e.templates.Clear()
+	e.files.Clear()
+}
+
+// 获取模板加载器
+func (e *Engine) Loader() Loader {
+	e.RLock()
+	defer e.RUnlock()
+	return e.loader
+}
+
+// 加载并解析模板，优先从缓存中获取
+func (e *Engine) loadTemplate(name string) (*GKTemplate, error) {
+	// 根据文件名获取存储哈希
+	khash := ""
+	h := sha1.New()
+	h.Write([]byte(name))
+	khash = fmt.Sprintf("%x", h.Sum(nil))
+
+	// 尝试从存储中载入模板
+	v := e.files.GetTemplateFile(khash)
+	if v == nil {
+		// 从加载器中载入模板
+		tplstr, err := e.Loader().Load(name)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("load file:", name)
+
+		e.files.SetTemplateFile(khash, &tplstr)
+		v = &tplstr
+	}
+
+	return e.parseTemplate(name, v, "", "", "", khash)
+}
+
+// 获取模板版本，模板内容或标签设置变化后版本改变，可以用于生成HTTP ETag
+// 版本只包含模板本身，不包含import导入的模板
+func (e *Engine) TemplateVersion(name string) (string, error) {
+	gktp, err := e.loadTemplate(name)
+	if err != nil {
+		return "", err
+	}
+	return gktp.version, nil
+}
+
+// 获取默认引擎中模板的版本
+func TemplateVersion(name string) (string, error) {
+	return defaultEngine.TemplateVersion(name)
+}
+
+// 解析模板名称，以./或../开头的名称相对于当前模板所在目录，其他名称直接交给加载器
+// 例：在news/list.htm中引用./macros.htm得到news/macros.htm
+func resolveName(current, name string) string {
+	name = filepath.ToSlash(name)
+	if !strings.HasPrefix(name, "./") && !strings.HasPrefix(name, "../") {
+		return name
+	}
+	return path.Join(path.Dir(filepath.ToSlash(current)), name)
+}
@@ -0,0 +1,676 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板解析器
+package gktemplate
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	attr "github.com/gokeeptech/gktemplate/attribute"
+)
+
+// 原样输出的块标签名称，例：<{gk:literal}>...<{/gk:literal}>
+const literalTagName = "literal"
+
+// 转义字符，放在标签开始标记之前表示原样输出开始标记，例：\<{gk:field/}>
+const escapeByte = '\\'
+
+// 去除空白标记，例：<{- gk:range name="list" -}>
+const trimByte = '-'
+
+// 模板注释标记，例：<{* 注释 *}>
+const commentByte = '*'
+
+// 块标签内部文本中数据项字段标记，例：[field:title/]
+const (
+	fieldNameSpace = "field"
+	fieldTagStart  = "["
+	fieldTagEnd    = "]"
+)
+
+// ParseError 模板解析错误，包含出错位置
+type ParseError struct {
+	Name string // 模板名称
+	Pos  int    // 字节位置
+	Line int    // 行号，从1开始
+	Col  int    // 列号，按字符计算，从1开始
+	Msg  string // 错误信息
+}
+
+func (pe *ParseError) Error() string {
+	if pe.Name == "" {
+		return fmt.Sprintf("[GKTemplate]line %d col %d: %s", pe.Line, pe.Col, pe.Msg)
+	}
+	return fmt.Sprintf("[GKTemplate]%s:%d:%d: %s", pe.Name, pe.Line, pe.Col, pe.Msg)
+}
+
+// 解析配置
+type parseConfig struct {
+	Name       string   // 模板名称，用于错误定位
+	NameSpaces []string // 可识别的命名空间，第一个为默认命名空间
+	TagStart   string   // 标签开始标记
+	TagEnd     string   // 标签结束标记
+	Field      bool     // 是否识别[field:name/]标记，用于块标签内部文本
+	Dede       bool     // DedeCMS兼容模式
+	TrimBlocks bool     // 去掉独占一行的块标签、注释所在行的缩进和换行
+	Root       string   // 最外层模板字符串，为空则为src
+	Offset     int      // src在Root中的位置
+}
+
+// 计算字节位置所在的行号、列号，列号按字符计算
+func position(src string, pos int) (int, int) {
+	if pos > len(src) {
+		pos = len(src)
+	}
+	line := 1 + strings.Count(src[:pos], "\n")
+	lineStart := strings.LastIndexByte(src[:pos], '\n') + 1
+	return line, 1 + utf8.RuneCountInString(src[lineStart:pos])
+}
+
+// 标签语法：开始、结束标记以及可识别的命名空间
+type syntax struct {
+	start      string // 标签开始标记
+	end        string // 标签结束标记
+	namespaces map[string]bool
+	field      bool // 是否为[field:name/]标记
+}
+
+// 语法的默认命名空间
+func (syn *syntax) namespaceOf(cfg *parseConfig) string {
+	if syn.field {
+		return fieldNameSpace
+	}
+	return cfg.NameSpaces[0]
+}
+
+// 模板扫描器
+type scanner struct {
+	cfg      *parseConfig
+	src      string
+	syntaxes []*syntax
+}
+
+func (sc *scanner) errorf(pos int, format string, args ...interface{}) error {
+	line, col := position(sc.src, pos)
+	return &ParseError{
+		Name: sc.cfg.Name,
+		Pos:  pos,
+		Line: line,
+		Col:  col,
+		Msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// 判断pos处是否为指定字符串
+func (sc *scanner) hasPrefix(pos int, word string) bool {
+	return pos >= 0 && pos <= len(sc.src) && strings.HasPrefix(sc.src[pos:], word)
+}
+
+// 从pos开始查找标签开始标记，syn不为nil时只查找该语法的标记
+func (sc *scanner) indexStart(pos int, syn *syntax) (int, *syntax) {
+	for i := pos; i < len(sc.src); i++ {
+		for _, s := range sc.syntaxes {
+			if syn != nil && s != syn {
+				continue
+			}
+			if sc.src[i] == s.start[0] && sc.hasPrefix(i, s.start) {
+				return i, s
+			}
+		}
+	}
+	return -1, nil
+}
+
+// 从pos开始查找标签结束标记，忽略引号中的内容
+func (sc *scanner) indexEnd(pos int, syn *syntax) int {
+	var quote byte
+	for i := pos; i < len(sc.src); i++ {
+		r := sc.src[i]
+		if quote != 0 {
+			if r == '\\' {
+				i++
+			} else if r == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '\'', '"', '`':
+			quote = r
+		default:
+			if r == syn.end[0] && sc.hasPrefix(i, syn.end) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameByte(r byte) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// 读取pos处的`命名空间:`，返回命名空间和冒号后的位置
+func (sc *scanner) readNameSpace(pos int, syn *syntax) (string, int) {
+	i := pos
+	for i < len(sc.src) && isNameByte(sc.src[i]) {
+		i++
+	}
+	if i == pos || i >= len(sc.src) || sc.src[i] != ':' {
+		return "", -1
+	}
+	ns := sc.src[pos:i]
+	if !syn.namespaces[ns] {
+		return "", -1
+	}
+	return ns, i + 1
+}
+
+// 标签头信息
+type tagHead struct {
+	NameSpace string // 命名空间
+	Closing   bool   // 是否为闭合标记`<{/gk:name}>`
+	Body      string // 标签名称及属性
+	SelfClose bool   // 是否自闭合
+	TrimLeft  bool   // 是否去掉标签之前的空白，例：<{- gk:field/}>
+	TrimRight bool   // 是否去掉标签之后的空白，例：<{gk:field/ -}>
+	Start     int    // 开始位置
+	End       int    // 结束标记之后的位置
+}
+
+// 读取pos处的标签头，不是标签则返回nil
+func (sc *scanner) readHead(pos int, syn *syntax) (*tagHead, error) {
+	head := tagHead{Start: pos}
+	i := pos + len(syn.start)
+	if !syn.field && i < len(sc.src) && sc.src[i] == trimByte {
+		head.TrimLeft = true
+		i = sc.skipSpace(i + 1)
+	}
+	if i < len(sc.src) && sc.src[i] == '/' {
+		head.Closing = true
+		i++
+	}
+	ns, i := sc.readNameSpace(i, syn)
+	if i == -1 {
+		return nil, nil
+	}
+	head.NameSpace = ns
+
+	e := sc.indexEnd(i, syn)
+	if e == -1 {
+		return nil, sc.errorf(pos, "tag '%s:' is not closed by '%s'", ns, syn.end)
+	}
+	head.End = e + len(syn.end)
+	head.Body = sc.src[i:e]
+	if !syn.field {
+		head.Body, head.TrimRight = trimMarker(head.Body)
+	}
+	if !head.Closing {
+		if ok, idx := IsEndOfForwardSlash(&head.Body); ok {
+			head.SelfClose = true
+			head.Body = head.Body[:idx]
+		}
+	}
+	if !syn.field && !head.TrimRight {
+		head.Body, head.TrimRight = trimMarker(head.Body)
+	}
+	return &head, nil
+}
+
+// 去掉标签体末尾的去除空白标记
+func trimMarker(body string) (string, bool) {
+	s := strings.TrimRight(body, " \t\r\n")
+	if strings.HasSuffix(s, string(trimByte)) {
+		return s[:len(s)-1], true
+	}
+	return body, false
+}
+
+// 跳过空白字符
+func (sc *scanner) skipSpace(pos int) int {
+	for pos < len(sc.src) && isSpace(sc.src[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func isSpace(r byte) bool {
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
+}
+
+// 读取pos处的注释`<{* 注释 *}>`，不是注释则返回nil
+func (sc *scanner) readComment(pos int, syn *syntax) (*tagHead, error) {
+	if syn.field {
+		return nil, nil
+	}
+	head := tagHead{Start: pos}
+	i := pos + len(syn.start)
+	if i < len(sc.src) && sc.src[i] == trimByte {
+		head.TrimLeft = true
+		i++
+	}
+	if i >= len(sc.src) || sc.src[i] != commentByte {
+		return nil, nil
+	}
+	for j := i + 1; j < len(sc.src); j++ {
+		if sc.src[j] != commentByte {
+			continue
+		}
+		k := j + 1
+		if k < len(sc.src) && sc.src[k] == trimByte && sc.hasPrefix(k+1, syn.end) {
+			head.TrimRight = true
+			k++
+		}
+		if sc.hasPrefix(k, syn.end) {
+			head.End = k + len(syn.end)
+			return &head, nil
+		}
+	}
+	return nil, sc.errorf(pos, "comment is not closed by '%c%s'", commentByte, syn.end)
+}
+
+// 计算标签[s,e)前后需要去掉的空白数量
+// 设置了去除空白标记时去掉相邻的所有空白；开启TrimBlocks且标签独占一行时去掉该行的缩进和换行
+func (sc *scanner) trimSpace(s, e int, left, right, block bool) (int, int) {
+	before, after := 0, 0
+	if left {
+		for i := s - 1; i >= 0 && isSpace(sc.src[i]); i-- {
+			before++
+		}
+	}
+	if right {
+		for i := e; i < len(sc.src) && isSpace(sc.src[i]); i++ {
+			after++
+		}
+	}
+	if !block || !sc.cfg.TrimBlocks {
+		return before, after
+	}
+
+	// 在最外层模板中判断是否独占一行
+	root, off := sc.cfg.Root, sc.cfg.Offset
+	if root == "" {
+		root, off = sc.src, 0
+	}
+	i := off + s - 1
+	for i >= 0 && (root[i] == ' ' || root[i] == '\t') {
+		i--
+	}
+	if i >= 0 && root[i] != '\n' {
+		return before, after
+	}
+	j := off + e
+	for j < len(root) && (root[j] == ' ' || root[j] == '\t' || root[j] == '\r') {
+		j++
+	}
+	if j < len(root) && root[j] != '\n' {
+		return before, after
+	}
+	if j < len(root) {
+		j++
+	}
+	if n := off + s - (i + 1); n > before {
+		before = n
+	}
+	if n := j - (off + e); n > after {
+		after = n
+	}
+	if before > s {
+		before = s
+	}
+	if after > len(sc.src)-e {
+		after = len(sc.src) - e
+	}
+	return before, after
+}
+
+// pos处的开始标记是否被转义：前面是`\`且后面是有效的标签头，
+// 其他情况的`\`按普通文本处理，例如`x\[y`、`C:\<{`
+func (sc *scanner) isEscaped(pos int, syn *syntax) bool {
+	return sc.escapes(pos) == 1 && sc.isTagHead(pos, syn)
+}
+
+// pos处的标签前是否为`\\`，表示输出`\`本身，标签照常解析，例：`C:\\<{gk:field name="dir"/}>`
+func (sc *scanner) isEscapedEscape(pos int, syn *syntax) bool {
+	return sc.escapes(pos) == 2 && sc.isTagHead(pos, syn)
+}
+
+// pos之前连续的转义字符个数，最多计2个
+func (sc *scanner) escapes(pos int) int {
+	n := 0
+	for n < 2 && pos-n > 0 && sc.src[pos-n-1] == escapeByte {
+		n++
+	}
+	return n
+}
+
+// pos处是否为有效的标签头，例：`<{gk:`、`<{/gk:`、`[field:`
+func (sc *scanner) isTagHead(pos int, syn *syntax) bool {
+	i := pos + len(syn.start)
+	if !syn.field && i < len(sc.src) && sc.src[i] == trimByte {
+		i = sc.skipSpace(i + 1)
+	}
+	if i < len(sc.src) && sc.src[i] == '/' {
+		i++
+	}
+	_, i = sc.readNameSpace(i, syn)
+	return i != -1
+}
+
+// 是否为literal块标签的开始标记
+func (sc *scanner) isLiteral(head *tagHead, syn *syntax) bool {
+	return !syn.field && !head.Closing && !head.SelfClose &&
+		head.NameSpace == sc.cfg.NameSpaces[0] && tagNameOf(head.Body) == literalTagName
+}
+
+// 查找literal块的闭合标记，块内容不解析，不支持嵌套
+func (sc *scanner) findLiteralClose(pos int, syn *syntax, ns string) (int, int) {
+	closing := syn.start + "/" + ns + ":" + literalTagName + syn.end
+	i := strings.Index(sc.src[pos:], closing)
+	if i == -1 {
+		return -1, -1
+	}
+	return pos + i, pos + i + len(closing)
+}
+
+// 查找块标签对应的闭合标记，支持同名标签嵌套，跳过转义的开始标记和literal块
+func (sc *scanner) findClose(pos int, syn *syntax, ns, name string) (*tagHead, error) {
+	depth := 1
+	for {
+		p, _ := sc.indexStart(pos, syn)
+		if p == -1 {
+			return nil, nil
+		}
+		if sc.isEscaped(p, syn) {
+			pos = p + len(syn.start)
+			continue
+		}
+		comment, err := sc.readComment(p, syn)
+		if err != nil {
+			return nil, err
+		}
+		if comment != nil {
+			pos = comment.End
+			continue
+		}
+		head, err := sc.readHead(p, syn)
+		if err != nil {
+			return nil, err
+		}
+		if head == nil {
+			pos = p + 1
+			continue
+		}
+		if sc.isLiteral(head, syn) {
+			_, ce := sc.findLiteralClose(head.End, syn, head.NameSpace)
+			if ce == -1 {
+				return nil, sc.errorf(p, "tag '%s:%s' is not closed", head.NameSpace, literalTagName)
+			}
+			pos = ce
+			continue
+		}
+		if head.NameSpace == ns && tagNameOf(head.Body) == name {
+			if head.Closing {
+				depth--
+				if depth == 0 {
+					return head, nil
+				}
+			} else if !head.SelfClose {
+				depth++
+			}
+		}
+		pos = head.End
+	}
+}
+
+// 取出标签体中的标签名称
+func tagNameOf(body string) string {
+	s := 0
+	for s < len(body) && isSpace(body[s]) {
+		s++
+	}
+	e := s
+	for e < len(body) && !isSpace(body[e]) {
+		e++
+	}
+	name := body[s:e]
+	if CharToLow {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// 块标签内部模板，首次渲染时解析
+type innerTemplate struct {
+	once sync.Once
+	tpl  *GKTemplate
+	err  error
+}
+
+// 解析模板源码，只解析最外层标签，块标签的内部文本由标签自行解析
+func parseSource(src string, cfg *parseConfig) (*GKTemplate, error) {
+	var gktpl = GKTemplate{}
+	gktpl.CTags = make(map[int]*GKTag)
+	gktpl.Count = 0
+	gktpl.source = src
+	gktpl.Name = cfg.Name
+	gktpl.NameSpace = cfg.NameSpaces[0]
+	gktpl.NameSpaces = cfg.NameSpaces
+	gktpl.TagStart = cfg.TagStart
+	gktpl.TagEnd = cfg.TagEnd
+	gktpl.root = src
+	gktpl.dede = cfg.Dede
+	gktpl.trimBlocks = cfg.TrimBlocks
+
+	// 校验标签
+	err := checkNameSpaceAndTag(&gktpl)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := scanner{cfg: cfg, src: src}
+	main := &syntax{
+		start:      cfg.TagStart,
+		end:        cfg.TagEnd,
+		namespaces: make(map[string]bool),
+	}
+	for _, ns := range cfg.NameSpaces {
+		main.namespaces[ns] = true
+	}
+	sc.syntaxes = append(sc.syntaxes, main)
+	if cfg.Field {
+		sc.syntaxes = append(sc.syntaxes, &syntax{
+			start:      fieldTagStart,
+			end:        fieldTagEnd,
+			namespaces: map[string]bool{fieldNameSpace: true},
+			field:      true,
+		})
+	}
+
+	pos := 0
+	for {
+		p, syn := sc.indexStart(pos, nil)
+		if p == -1 {
+			break
+		}
+		if sc.isEscapedEscape(p, syn) {
+			// `\\`只输出一个`\`，之后的标签照常解析
+			gktpl.CTags[gktpl.Count] = &GKTag{
+				NameSpace:  syn.namespaceOf(cfg),
+				TagName:    literalTagName,
+				CAttribute: &attr.Attribute{Items: map[string]string{"tagname": literalTagName}},
+				innerText:  string(escapeByte),
+				InnerPos:   p - 1,
+				StartPos:   p - 2,
+				EndPos:     p,
+				TagID:      gktpl.Count,
+				literal:    true,
+				tpl:        &gktpl,
+			}
+			gktpl.Count++
+		} else if sc.isEscaped(p, syn) {
+			// 转义的开始标记原样输出，不包括转义字符
+			gktpl.CTags[gktpl.Count] = &GKTag{
+				NameSpace:  syn.namespaceOf(cfg),
+				TagName:    literalTagName,
+				CAttribute: &attr.Attribute{Items: map[string]string{"tagname": literalTagName}},
+				innerText:  syn.start,
+				InnerPos:   p,
+				StartPos:   p - 1,
+				EndPos:     p + len(syn.start),
+				TagID:      gktpl.Count,
+				literal:    true,
+				tpl:        &gktpl,
+			}
+			gktpl.Count++
+			pos = p + len(syn.start)
+			continue
+		}
+		comment, err := sc.readComment(p, syn)
+		if err != nil {
+			return nil, err
+		}
+		if comment != nil {
+			// 注释不输出
+			gktag := GKTag{
+				NameSpace:  syn.namespaceOf(cfg),
+				TagName:    literalTagName,
+				CAttribute: &attr.Attribute{Items: map[string]string{"tagname": literalTagName}},
+				InnerPos:   comment.End,
+				StartPos:   comment.Start,
+				EndPos:     comment.End,
+				TagID:      gktpl.Count,
+				literal:    true,
+				tpl:        &gktpl,
+			}
+			gktag.trimBefore, gktag.trimAfter = sc.trimSpace(comment.Start, comment.End, comment.TrimLeft, comment.TrimRight, true)
+			gktpl.CTags[gktpl.Count] = &gktag
+			gktpl.Count++
+			pos = comment.End
+			continue
+		}
+		head, err := sc.readHead(p, syn)
+		if err != nil {
+			return nil, err
+		}
+		if head == nil {
+			pos = p + 1
+			continue
+		}
+		if head.Closing {
+			return nil, sc.errorf(p, "unexpected closing tag '%s:%s'", head.NameSpace, tagNameOf(head.Body))
+		}
+
+		cAtt, err := attr.Parse(head.Body)
+		if err != nil {
+			return nil, sc.errorf(p, "tag '%s:' %s", head.NameSpace, err)
+		}
+		if cfg.Dede {
+			cAtt = dedeAttribute(cAtt)
+		}
+
+		var gktag = GKTag{
+			NameSpace:  head.NameSpace,
+			TagName:    cAtt.GetTagName(),
+			CAttribute: cAtt,
+			StartPos:   head.Start,
+			EndPos:     head.End,
+			TagID:      gktpl.Count,
+			InnerPos:   -1,
+			isField:    syn.field,
+			tpl:        &gktpl,
+		}
+
+		block := !head.SelfClose
+		var closing *tagHead
+		if sc.isLiteral(head, syn) {
+			// literal块，内部文本原样输出
+			cs, ce := sc.findLiteralClose(head.End, syn, head.NameSpace)
+			if cs == -1 {
+				return nil, sc.errorf(p, "tag '%s:%s' is not closed", head.NameSpace, literalTagName)
+			}
+			closing = &tagHead{Start: cs, End: ce}
+			gktag.literal = true
+		} else if block {
+			// 块标签，查找闭合标记并取出内部文本
+			closing, err = sc.findClose(head.End, syn, head.NameSpace, tagNameOf(head.Body))
+			if err != nil {
+				return nil, err
+			}
+			if closing == nil {
+				return nil, sc.errorf(p, "tag '%s:%s' is not closed", head.NameSpace, gktag.TagName)
+			}
+			gktag.inner = &innerTemplate{}
+		}
+
+		// 去除空白，块标签内部文本的首尾空白直接从内部文本中去掉
+		gktag.trimBefore, gktag.trimAfter = sc.trimSpace(head.Start, head.End, head.TrimLeft, head.TrimRight, block)
+		if closing != nil {
+			innerStart := head.End + gktag.trimAfter
+			innerEnd, after := sc.trimSpace(closing.Start, closing.End, closing.TrimLeft, closing.TrimRight, true)
+			innerEnd = closing.Start - innerEnd
+			if innerEnd < innerStart {
+				innerEnd = innerStart
+			}
+			gktag.innerText = src[innerStart:innerEnd]
+			gktag.InnerPos = innerStart
+			gktag.EndPos = closing.End
+			gktag.trimAfter = after
+		}
+
+		gktpl.CTags[gktpl.Count] = &gktag
+		gktpl.Count++
+		pos = gktag.EndPos
+	}
+
+	return &gktpl, nil
+}
+
+// 解析块标签的内部文本，内部模板可以使用外层模板的标签以及[field:name/]标记
+// 解析结果保存在标签中，出错位置换算为最外层模板中的位置
+func (gktag *GKTag) parseInner() (*GKTemplate, error) {
+	// 块标签的inner在解析时创建，这里不修改可能被并发渲染共用的标签
+	in := gktag.inner
+	if in == nil {
+		in = &innerTemplate{}
+	}
+	in.once.Do(func() {
+		outer := gktag.tpl
+		cfg := &parseConfig{
+			NameSpaces: []string{defaultNameSpace},
+			TagStart:   defaultTagStart,
+			TagEnd:     defaultTagEnd,
+			Field:      true,
+		}
+		offset := 0
+		var root string
+		if outer != nil {
+			cfg.Name = outer.Name
+			cfg.NameSpaces = outer.NameSpaces
+			cfg.TagStart = outer.TagStart
+			cfg.TagEnd = outer.TagEnd
+			cfg.Dede = outer.dede
+			cfg.TrimBlocks = outer.trimBlocks
+			offset = outer.offset + gktag.InnerPos
+			root = outer.root
+			cfg.Root = root
+			cfg.Offset = offset
+		}
+		in.tpl, in.err = parseSource(gktag.innerText, cfg)
+		if pe, ok := in.err.(*ParseError); ok && root != "" {
+			pe.Pos += offset
+			pe.Line, pe.Col = position(root, pe.Pos)
+		}
+		if in.tpl != nil {
+			in.tpl.offset = offset
+			if root != "" {
+				in.tpl.root = root
+			}
+		}
+	})
+	return in.tpl, in.err
+}
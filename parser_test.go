@@ -0,0 +1,185 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板解析器单元测试
+package gktemplate
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLiteral(t *testing.T) {
+	tpl := `<{gk:field name="a"/}>
+<{gk:literal}><div v-if="x <{ y }>">{{ msg }}</div><{gk:field name="a"/}><{/gk:range}><{/gk:literal}>
+\<{gk:field name="a"/}>
+<{gk:range name="list"}><{gk:literal}><{/gk:range}><{/gk:literal}>[field:a/]\[field:a/]<{/gk:range}>`
+	rs, err := ParseString(tpl, D{"a": "A", "list": []D{{"a": "B"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `A
+<div v-if="x <{ y }>">{{ msg }}</div><{gk:field name="a"/}><{/gk:range}>
+<{gk:field name="a"/}>
+<{/gk:range}>B[field:a/]`
+	if rs != expect {
+		t.Errorf("literal result=%q, expect %q", rs, expect)
+	}
+	if diags := Validate("literal.htm", tpl); len(diags) != 0 {
+		t.Errorf("literal diags=%v", diags)
+	}
+}
+
+func TestLiteralErrorPosition(t *testing.T) {
+	tpl := "<{gk:literal}>\n<{gk:bad\n<{/gk:literal}>\n\\<{gk:x\n  <{gk:range name=\"list\"}>"
+	_, err := ParseString(tpl, nil)
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expect ParseError, got %v", err)
+	}
+	if pe.Line != 5 || pe.Col != 3 {
+		t.Errorf("error position=%d:%d, expect 5:3", pe.Line, pe.Col)
+	}
+
+	_, err = ParseString("a\n<{gk:literal}>b", nil)
+	if pe, ok := err.(*ParseError); !ok || pe.Line != 2 || pe.Col != 1 {
+		t.Errorf("unclosed literal error=%v", err)
+	}
+}
+
+// 位置按字节计算，列号按字符计算
+func TestErrorPositionUTF8(t *testing.T) {
+	_, err := ParseString("标题\n中文<{gk:range name=\"list\"}>", nil)
+	pe, ok := err.(*ParseError)
+	if !ok || pe.Pos != len("标题\n中文") || pe.Line != 2 || pe.Col != 3 {
+		t.Errorf("parse error=%v", err)
+	}
+
+	_, err = ParseString("<{gk:range name=\"list\"}>\n  列表：<{gk:call name=\"none\"/}><{/gk:range}>", D{"list": []D{{}}})
+	re, ok := err.(*RenderError)
+	if !ok || re.Line != 2 || re.Col != 6 {
+		t.Errorf("render error=%v", err)
+	}
+
+	rs, err := ParseString("<{gk:range name=\"list\"}>「[field:name/]」<{/gk:range}>", D{"list": []D{{"name": "模板"}}})
+	if err != nil || rs != "「模板」" {
+		t.Errorf("utf8 result=%q, err=%v", rs, err)
+	}
+}
+
+func TestComment(t *testing.T) {
+	tpl := "a<{* note <{gk:field name=\"a\"/}> *}>b<{gk:range name=\"list\"}><{* <{/gk:range}> *}>[field:a/]<{/gk:range}>"
+	rs, err := ParseString(tpl, D{"list": []D{{"a": "1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs != "ab1" {
+		t.Errorf("comment result=%q", rs)
+	}
+
+	_, err = ParseString("a\n<{* note }>", nil)
+	if pe, ok := err.(*ParseError); !ok || pe.Line != 2 || pe.Col != 1 {
+		t.Errorf("unclosed comment error=%v", err)
+	}
+}
+
+func TestTrimMarker(t *testing.T) {
+	tpl := "<ul>\n  <{- gk:range name=\"list\" -}>\n  <li>[field:a/]</li>\n  <{- /gk:range -}>\n</ul> <{-* x *-}> <{gk:field name=\"b\" /-}> !"
+	rs, err := ParseString(tpl, D{"b": "B", "list": []D{{"a": "1"}, {"a": "2"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "<ul><li>1</li><li>2</li></ul>B!"; rs != expect {
+		t.Errorf("trim result=%q, expect %q", rs, expect)
+	}
+}
+
+func TestTrimBlocks(t *testing.T) {
+	e := New()
+	e.SetTrimBlocks(true)
+	tpl := `<ul>
+  <{* 列表 *}>
+  <{gk:range name="list"}>
+    <{gk:with name="item"}>
+    <li><{gk:field name="a"/}></li>
+    <{/gk:with}>
+  <{/gk:range}>
+</ul>
+<{gk:range name="list"}>[field:a/] <{/gk:range}>
+`
+	data := D{"list": []D{{"item": D{"a": "1"}}, {"item": D{"a": "2"}}}}
+	rs, err := e.ParseString(tpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "<ul>\n    <li>1</li>\n    <li>2</li>\n</ul>\n  \n"
+	if rs != expect {
+		t.Errorf("trim blocks result=%q, expect %q", rs, expect)
+	}
+
+	// 解析错误位置不受影响
+	_, err = e.ParseString("<{gk:range name=\"list\"}>\n  <{gk:with name=\"item\"}>\n<{/gk:range}>", data)
+	if pe, ok := err.(*ParseError); !ok || pe.Line != 2 || pe.Col != 3 {
+		t.Errorf("trim blocks error=%v", err)
+	}
+}
+
+// 只有标签头之前的`\`才是转义，`\\`输出`\`本身
+func TestEscape(t *testing.T) {
+	cases := []struct {
+		tpl    string
+		expect string
+	}{
+		{`x\[y <{gk:field name="a"/}>`, `x\[y A`},
+		{`C:\temp\<{ x }> C:\\<{gk:field name="a"/}>`, `C:\temp\<{ x }> C:\A`},
+		{`\<{gk:field name="a"/}>`, `<{gk:field name="a"/}>`},
+		{`<{gk:range name="list"}>C:\[x] D:\\[field:a/] \[field:a/]<{/gk:range}>`, `C:\[x] D:\B [field:a/]`},
+	}
+	for _, c := range cases {
+		rs, err := ParseString(c.tpl, D{"a": "A", "list": []D{{"a": "B"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rs != c.expect {
+			t.Errorf("%s result=%q, expect %q", c.tpl, rs, c.expect)
+		}
+	}
+}
+
+// 内部文本同时提供[]rune和string两种形式，[]rune在调用时才转换
+func TestInnerText(t *testing.T) {
+	src := `a<{gk:range name="list"}>中文[field:a/]<{/gk:range}>`
+	gktpl, err := parseSource(src, &parseConfig{NameSpaces: []string{"gk"}, TagStart: "<{", TagEnd: "}>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gktpl.SourceString != nil || gktpl.Source() != src {
+		t.Errorf("source=%q, %q", string(gktpl.SourceString), gktpl.Source())
+	}
+	tag := gktpl.CTags[0]
+	if tag.InnerText != nil {
+		t.Errorf("InnerText should not be filled when parsing")
+	}
+	if string(tag.GetInnerText()) != "中文[field:a/]" || tag.GetInnerString() != "中文[field:a/]" {
+		t.Errorf("inner text=%q, %q", string(tag.GetInnerText()), tag.GetInnerString())
+	}
+}
+
+// 缓存的模板被并发渲染时，块标签的内部模板只解析一次
+func TestParseInnerConcurrent(t *testing.T) {
+	e := New()
+	tpl := `<{gk:range name="list"}><{gk:with name="item"}>[field:a/]<{/gk:with}><{/gk:range}>`
+	data := D{"list": []D{{"item": D{"a": 1}}, {"item": D{"a": 2}}}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rs, err := e.ParseStringWithNameSpace(&tpl, data, "", "", "", "inner")
+			if err != nil || rs != "12" {
+				t.Errorf("concurrent result=%q, err=%v", rs, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
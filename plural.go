@@ -0,0 +1,193 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// CLDR复数规则
+package gktemplate
+
+import (
+	"math"
+	"strings"
+	"sync"
+)
+
+// CLDR复数类别
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// PluralFunc 根据数量返回复数类别
+type PluralFunc func(n float64) string
+
+// 复数规则，categories为fn返回的全部类别，forms为PO文件默认的Plural-Forms
+type pluralRule struct {
+	categories []string
+	fn         PluralFunc
+	forms      string
+}
+
+// 下面定义一个存储结构体，保存各语言的复数规则
+type pluralStorage struct {
+	Items map[string]pluralRule // 存储结构，键为语言，例：en
+	sync.RWMutex
+}
+
+var plurals = pluralStorage{Items: make(map[string]pluralRule)}
+
+// 数量的整数部分，以及是否有小数
+func pluralOperands(n float64) (i int64, frac bool) {
+	n = math.Abs(n)
+	return int64(n), n != math.Trunc(n)
+}
+
+func pluralOther(n float64) string {
+	return PluralOther
+}
+
+// 英语、德语等：1为one
+func pluralOneOther(n float64) string {
+	if i, frac := pluralOperands(n); i == 1 && !frac {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+// 法语：0、1为one
+func pluralFrench(n float64) string {
+	if i, _ := pluralOperands(n); i == 0 || i == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+// 俄语、乌克兰语
+func pluralRussian(n float64) string {
+	i, frac := pluralOperands(n)
+	switch {
+	case frac:
+		return PluralOther
+	case i%10 == 1 && i%100 != 11:
+		return PluralOne
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return PluralFew
+	}
+	return PluralMany
+}
+
+// 波兰语
+func pluralPolish(n float64) string {
+	i, frac := pluralOperands(n)
+	switch {
+	case frac:
+		return PluralOther
+	case i == 1:
+		return PluralOne
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return PluralFew
+	}
+	return PluralMany
+}
+
+// 捷克语、斯洛伐克语
+func pluralCzech(n float64) string {
+	i, frac := pluralOperands(n)
+	switch {
+	case frac:
+		return PluralMany
+	case i == 1:
+		return PluralOne
+	case i >= 2 && i <= 4:
+		return PluralFew
+	}
+	return PluralOther
+}
+
+// 阿拉伯语
+func pluralArabic(n float64) string {
+	i, frac := pluralOperands(n)
+	switch {
+	case frac:
+		return PluralOther
+	case i == 0:
+		return PluralZero
+	case i == 1:
+		return PluralOne
+	case i == 2:
+		return PluralTwo
+	case i%100 >= 3 && i%100 <= 10:
+		return PluralFew
+	case i%100 >= 11:
+		return PluralMany
+	}
+	return PluralOther
+}
+
+func init() {
+	other := []string{PluralOther}
+	for _, lang := range []string{"zh", "ja", "ko", "vi", "th", "id", "ms"} {
+		plurals.Items[lang] = pluralRule{other, pluralOther, "nplurals=1; plural=0;"}
+	}
+	oneOther := []string{PluralOne, PluralOther}
+	for _, lang := range []string{"en", "de", "nl", "sv", "da", "nb", "no", "fi", "it", "es", "el", "hu", "tr"} {
+		plurals.Items[lang] = pluralRule{oneOther, pluralOneOther, "nplurals=2; plural=(n != 1);"}
+	}
+	for _, lang := range []string{"fr", "pt"} {
+		plurals.Items[lang] = pluralRule{oneOther, pluralFrench, "nplurals=2; plural=(n > 1);"}
+	}
+	slavic := []string{PluralOne, PluralFew, PluralMany, PluralOther}
+	russian := "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);"
+	plurals.Items["ru"] = pluralRule{slavic, pluralRussian, russian}
+	plurals.Items["uk"] = pluralRule{slavic, pluralRussian, russian}
+	plurals.Items["pl"] = pluralRule{slavic, pluralPolish, "nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);"}
+	czech := "nplurals=3; plural=(n==1) ? 0 : (n>=2 && n<=4) ? 1 : 2;"
+	plurals.Items["cs"] = pluralRule{slavic, pluralCzech, czech}
+	plurals.Items["sk"] = pluralRule{slavic, pluralCzech, czech}
+	plurals.Items["ar"] = pluralRule{[]string{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther}, pluralArabic,
+		"nplurals=6; plural=(n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5);"}
+}
+
+// 注册语言的复数规则，categories为f返回的全部类别
+// PO文件没有Plural-Forms头部时，msgstr[n]按categories的顺序对应
+func RegisterPluralRule(lang string, categories []string, f PluralFunc) {
+	plurals.Lock()
+	defer plurals.Unlock()
+	plurals.Items[strings.ToLower(lang)] = pluralRule{categories, f, ""}
+}
+
+// 获取语言的复数规则，先按完整名称查找，再按语言查找，例：pt-BR、pt，未注册的语言只有other类别
+func lookupPlural(locale string) pluralRule {
+	plurals.RLock()
+	defer plurals.RUnlock()
+	locale = strings.ToLower(strings.Replace(locale, "_", "-", -1))
+	if r, ok := plurals.Items[locale]; ok {
+		return r
+	}
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		if r, ok := plurals.Items[locale[:i]]; ok {
+			return r
+		}
+	}
+	return pluralRule{[]string{PluralOther}, pluralOther, "nplurals=1; plural=0;"}
+}
+
+// 获取数量在语言中的复数类别，例：PluralCategory("ru", 3)返回few
+func PluralCategory(locale string, n float64) string {
+	return lookupPlural(locale).fn(n)
+}
+
+// 获取语言使用的全部复数类别，例：俄语返回one、few、many以及小数使用的other
+func PluralCategories(locale string) []string {
+	return append([]string(nil), lookupPlural(locale).categories...)
+}
+
+// 获取语言PO文件的Plural-Forms以及msgstr[n]对应的复数类别
+// 例：英语返回"nplurals=2; plural=(n != 1);"和one、other，RegisterPluralRule注册的规则forms为空
+func PluralForms(locale string) (forms string, categories []string) {
+	rule := lookupPlural(locale)
+	_, order, _ := pluralIndex(rule, rule.forms)
+	return rule.forms, append([]string(nil), order...)
+}
@@ -0,0 +1,241 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// PO文件的Plural-Forms
+package gktemplate
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// 计算复数类别对应关系时检查的数量范围
+const pluralFormsScan = 1000
+
+var errPluralForms = errors.New("invalid Plural-Forms")
+
+// Plural-Forms中的plural表达式，根据数量返回msgstr[n]的序号
+type pluralExpr func(n int64) int64
+
+// 解析Plural-Forms，例：nplurals=2; plural=(n != 1);
+func parsePluralForms(s string) (int, pluralExpr, error) {
+	nplurals, plural := 0, pluralExpr(nil)
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(part, "nplurals="):
+			n, err := strconv.Atoi(strings.TrimSpace(part[9:]))
+			if err != nil || n <= 0 {
+				return 0, nil, errPluralForms
+			}
+			nplurals = n
+		case strings.HasPrefix(part, "plural="):
+			p := &pluralParser{src: part[7:]}
+			plural = p.ternary()
+			if p.skip(); p.err == nil && p.pos < len(p.src) {
+				p.err = errPluralForms
+			}
+			if p.err != nil {
+				return 0, nil, p.err
+			}
+		}
+	}
+	if nplurals == 0 || plural == nil {
+		return 0, nil, errPluralForms
+	}
+	return nplurals, plural, nil
+}
+
+// 复数类别与msgstr[n]序号的对应关系
+// 按Plural-Forms计算0到1000的序号，同一序号取最先出现的类别；
+// 规则中其余的类别（例：俄语小数使用的other）使用最后一个msgstr。
+// forms为空时按规则的类别顺序对应
+func pluralIndex(rule pluralRule, forms string) (map[string]int, []string, error) {
+	index := make(map[string]int, len(rule.categories))
+	if forms == "" {
+		for i, c := range rule.categories {
+			index[c] = i
+		}
+		return index, rule.categories, nil
+	}
+	nplurals, plural, err := parsePluralForms(forms)
+	if err != nil {
+		return nil, nil, err
+	}
+	order := make([]string, nplurals)
+	for n := int64(0); n <= pluralFormsScan; n++ {
+		i := plural(n)
+		if i < 0 || i >= int64(nplurals) {
+			continue
+		}
+		c := rule.fn(float64(n))
+		if _, ok := index[c]; !ok {
+			index[c] = int(i)
+		}
+		if order[i] == "" {
+			order[i] = c
+		}
+	}
+	for _, c := range rule.categories {
+		if _, ok := index[c]; !ok {
+			index[c] = nplurals - 1
+		}
+	}
+	for i, c := range order {
+		if c == "" {
+			order[i] = PluralOther
+		}
+	}
+	return index, order, nil
+}
+
+// plural表达式的解析器，支持C语言的整数运算：?:、||、&&、==、!=、<、<=、>、>=、+、-、*、/、%、!
+type pluralParser struct {
+	src string
+	pos int
+	err error
+}
+
+func (p *pluralParser) skip() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// 读取运算符，成功时返回true
+func (p *pluralParser) accept(op string) bool {
+	p.skip()
+	if p.err == nil && strings.HasPrefix(p.src[p.pos:], op) {
+		p.pos += len(op)
+		return true
+	}
+	return false
+}
+
+func (p *pluralParser) ternary() pluralExpr {
+	cond := p.binary(0)
+	if !p.accept("?") {
+		return cond
+	}
+	x := p.ternary()
+	if !p.accept(":") {
+		p.err = errPluralForms
+		return nil
+	}
+	y := p.ternary()
+	return func(n int64) int64 {
+		if cond(n) != 0 {
+			return x(n)
+		}
+		return y(n)
+	}
+}
+
+// 二元运算符，按优先级从低到高排列，同一优先级中较长的运算符在前
+var pluralOps = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *pluralParser) binary(level int) pluralExpr {
+	if level == len(pluralOps) {
+		return p.unary()
+	}
+	x := p.binary(level + 1)
+	for p.err == nil {
+		op := ""
+		for _, o := range pluralOps[level] {
+			if p.accept(o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			break
+		}
+		x = pluralBinary(op, x, p.binary(level+1))
+	}
+	return x
+}
+
+func (p *pluralParser) unary() pluralExpr {
+	switch {
+	case p.accept("!"):
+		x := p.unary()
+		return func(n int64) int64 { return pluralBool(x(n) == 0) }
+	case p.accept("-"):
+		x := p.unary()
+		return func(n int64) int64 { return -x(n) }
+	case p.accept("("):
+		x := p.ternary()
+		if !p.accept(")") {
+			p.err = errPluralForms
+		}
+		return x
+	case p.accept("n"):
+		return func(n int64) int64 { return n }
+	}
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	v, err := strconv.ParseInt(p.src[start:p.pos], 10, 64)
+	if err != nil {
+		p.err = errPluralForms
+		return nil
+	}
+	return func(int64) int64 { return v }
+}
+
+func pluralBool(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// 二元运算，除数为0时结果为0
+func pluralBinary(op string, x, y pluralExpr) pluralExpr {
+	switch op {
+	case "||":
+		return func(n int64) int64 { return pluralBool(x(n) != 0 || y(n) != 0) }
+	case "&&":
+		return func(n int64) int64 { return pluralBool(x(n) != 0 && y(n) != 0) }
+	case "==":
+		return func(n int64) int64 { return pluralBool(x(n) == y(n)) }
+	case "!=":
+		return func(n int64) int64 { return pluralBool(x(n) != y(n)) }
+	case "<=":
+		return func(n int64) int64 { return pluralBool(x(n) <= y(n)) }
+	case ">=":
+		return func(n int64) int64 { return pluralBool(x(n) >= y(n)) }
+	case "<":
+		return func(n int64) int64 { return pluralBool(x(n) < y(n)) }
+	case ">":
+		return func(n int64) int64 { return pluralBool(x(n) > y(n)) }
+	case "+":
+		return func(n int64) int64 { return x(n) + y(n) }
+	case "-":
+		return func(n int64) int64 { return x(n) - y(n) }
+	case "*":
+		return func(n int64) int64 { return x(n) * y(n) }
+	case "/":
+		return func(n int64) int64 {
+			if d := y(n); d != 0 {
+				return x(n) / d
+			}
+			return 0
+		}
+	}
+	return func(n int64) int64 {
+		if d := y(n); d != 0 {
+			return x(n) % d
+		}
+		return 0
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 标签、函数注册表
+package gktemplate
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Errors
+var (
+	errLibNameEmpty  = errors.New("gktemplate tag name is empty")
+	errLibNil        = errors.New("gktemplate tag lib is nil")
+	errLibExists     = errors.New("gktemplate tag already exists")
+	errFuncNameEmpty = errors.New("gktemplate func name is empty")
+	errFuncNil       = errors.New("gktemplate func is nil")
+	errFuncExists    = errors.New("gktemplate func already exists")
+)
+
+// 标签注册表，读写均加锁，渲染过程中可以安全地注册、替换、删除
+type libRegistry struct {
+	version uint64            // 修改次数，已编译的模板据此重新查找标签
+	Items   map[string]TagLib // 存储结构
+	sync.RWMutex
+}
+
+func newLibRegistry() *libRegistry {
+	return &libRegistry{Items: make(map[string]TagLib)}
+}
+
+// 获取标签
+func (lr *libRegistry) Get(name string) (TagLib, bool) {
+	lr.RLock()
+	defer lr.RUnlock()
+	lib, ok := lr.Items[name]
+	return lib, ok
+}
+
+// 注册标签，已存在则返回错误
+func (lr *libRegistry) Add(name string, lib TagLib) error {
+	if name == "" {
+		return errLibNameEmpty
+	}
+	if lib == nil {
+		return errLibNil
+	}
+	lr.Lock()
+	defer lr.Unlock()
+	if _, ok := lr.Items[name]; ok {
+		return errLibExists
+	}
+	lr.Items[name] = lib
+	atomic.AddUint64(&lr.version, 1)
+	return nil
+}
+
+// 批量注册标签，任意一个已存在则全部不注册
+func (lr *libRegistry) AddAll(libs map[string]TagLib) error {
+	lr.Lock()
+	defer lr.Unlock()
+	for name, lib := range libs {
+		if name == "" {
+			return errLibNameEmpty
+		}
+		if lib == nil {
+			return errLibNil
+		}
+		if _, ok := lr.Items[name]; ok {
+			return errLibExists
+		}
+	}
+	for name, lib := range libs {
+		lr.Items[name] = lib
+	}
+	atomic.AddUint64(&lr.version, 1)
+	return nil
+}
+
+// 注册或覆盖标签，返回被覆盖的标签
+func (lr *libRegistry) Set(name string, lib TagLib) (TagLib, error) {
+	if name == "" {
+		return nil, errLibNameEmpty
+	}
+	if lib == nil {
+		return nil, errLibNil
+	}
+	lr.Lock()
+	defer lr.Unlock()
+	old := lr.Items[name]
+	lr.Items[name] = lib
+	atomic.AddUint64(&lr.version, 1)
+	return old, nil
+}
+
+// 删除标签
+func (lr *libRegistry) Delete(name string) bool {
+	lr.Lock()
+	defer lr.Unlock()
+	_, ok := lr.Items[name]
+	if ok {
+		delete(lr.Items, name)
+		atomic.AddUint64(&lr.version, 1)
+	}
+	return ok
+}
+
+// 已注册标签名称，按名称排序
+func (lr *libRegistry) Names() []string {
+	lr.RLock()
+	defer lr.RUnlock()
+	names := make([]string, 0, len(lr.Items))
+	for name := range lr.Items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 函数注册表
+type funcRegistry struct {
+	version uint64             // 修改次数，已编译的模板据此重新查找函数
+	Items   map[string]TagFunc // 存储结构
+	sync.RWMutex
+}
+
+func newFuncRegistry() *funcRegistry {
+	return &funcRegistry{Items: make(map[string]TagFunc)}
+}
+
+// 获取函数
+func (fr *funcRegistry) Get(name string) (TagFunc, bool) {
+	fr.RLock()
+	defer fr.RUnlock()
+	f, ok := fr.Items[name]
+	return f, ok
+}
+
+// 注册函数，已存在则返回错误
+func (fr *funcRegistry) Add(name string, f TagFunc) error {
+	if name == "" {
+		return errFuncNameEmpty
+	}
+	if f == nil {
+		return errFuncNil
+	}
+	fr.Lock()
+	defer fr.Unlock()
+	if _, ok := fr.Items[name]; ok {
+		return errFuncExists
+	}
+	fr.Items[name] = f
+	atomic.AddUint64(&fr.version, 1)
+	return nil
+}
+
+// 批量注册函数，任意一个已存在则全部不注册
+func (fr *funcRegistry) AddAll(funcs map[string]TagFunc) error {
+	fr.Lock()
+	defer fr.Unlock()
+	for name, f := range funcs {
+		if name == "" {
+			return errFuncNameEmpty
+		}
+		if f == nil {
+			return errFuncNil
+		}
+		if _, ok := fr.Items[name]; ok {
+			return errFuncExists
+		}
+	}
+	for name, f := range funcs {
+		fr.Items[name] = f
+	}
+	atomic.AddUint64(&fr.version, 1)
+	return nil
+}
+
+// 注册或覆盖函数，返回被覆盖的函数
+func (fr *funcRegistry) Set(name string, f TagFunc) (TagFunc, error) {
+	if name == "" {
+		return nil, errFuncNameEmpty
+	}
+	if f == nil {
+		return nil, errFuncNil
+	}
+	fr.Lock()
+	defer fr.Unlock()
+	old := fr.Items[name]
+	fr.Items[name] = f
+	atomic.AddUint64(&fr.version, 1)
+	return old, nil
+}
+
+// 删除函数
+func (fr *funcRegistry) Delete(name string) bool {
+	fr.Lock()
+	defer fr.Unlock()
+	_, ok := fr.Items[name]
+	if ok {
+		delete(fr.Items, name)
+		atomic.AddUint64(&fr.version, 1)
+	}
+	return ok
+}
+
+// 已注册函数名称，按名称排序
+func (fr *funcRegistry) Names() []string {
+	fr.RLock()
+	defer fr.RUnlock()
+	names := make([]string, 0, len(fr.Items))
+	for name := range fr.Items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 注册模板标签，名称已存在则返回错误
+func RegisterLib(name string, lib TagLib) error {
+	return defaultEngine.RegisterLib(name, lib)
+}
+
+// 注册或覆盖模板标签（包括内置的field、range），返回被覆盖的标签
+func ReplaceLib(name string, lib TagLib) (TagLib, error) {
+	return defaultEngine.ReplaceLib(name, lib)
+}
+
+// 删除模板标签，返回标签是否存在
+func UnregisterLib(name string) bool {
+	return defaultEngine.UnregisterLib(name)
+}
+
+// 获取模板标签
+func LookupLib(name string) (TagLib, bool) {
+	return defaultEngine.LookupLib(name)
+}
+
+// 列出已注册的模板标签
+func Libs() []string {
+	return defaultEngine.Libs()
+}
+
+// 注册模板函数，名称已存在则返回错误
+func RegisterFunc(name string, f TagFunc) error {
+	return defaultEngine.RegisterFunc(name, f)
+}
+
+// 注册或覆盖模板函数，返回被覆盖的函数
+func ReplaceFunc(name string, f TagFunc) (TagFunc, error) {
+	return defaultEngine.ReplaceFunc(name, f)
+}
+
+// 删除模板函数，返回函数是否存在
+func UnregisterFunc(name string) bool {
+	return defaultEngine.UnregisterFunc(name)
+}
+
+// 获取模板函数
+func LookupFunc(name string) (TagFunc, bool) {
+	return defaultEngine.LookupFunc(name)
+}
+
+// 列出已注册的模板函数
+func Funcs() []string {
+	return defaultEngine.Funcs()
+}
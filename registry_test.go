@@ -0,0 +1,143 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 注册表单元测试
+package gktemplate
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRegisterLib(t *testing.T) {
+	hello := func(tag *GKTag, data *D) string {
+		return "hello " + tag.GetAttribute("name")
+	}
+	if err := RegisterLib("reghello", hello); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterLib("reghello")
+
+	if err := RegisterLib("reghello", hello); err == nil {
+		t.Errorf("register an existing tag should return error")
+	}
+
+	rs, err := ParseString(`<{gk:reghello name="gk"/}>`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs != "hello gk" {
+		t.Errorf("RegisterLib result=%q", rs)
+	}
+
+	found := false
+	for _, name := range Libs() {
+		if name == "reghello" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Libs() should list reghello")
+	}
+
+	if !UnregisterLib("reghello") {
+		t.Errorf("UnregisterLib should report existing tag")
+	}
+	if _, ok := LookupLib("reghello"); ok {
+		t.Errorf("tag should be removed")
+	}
+}
+
+// 删除不存在的名称不应使已编译的模板失效
+func TestUnregisterMissing(t *testing.T) {
+	lr := newLibRegistry()
+	fr := newFuncRegistry()
+	if lr.Delete("missing") || fr.Delete("missing") {
+		t.Errorf("Delete should report missing name")
+	}
+	if lr.version != 0 || fr.version != 0 {
+		t.Errorf("version changed: lib=%d func=%d", lr.version, fr.version)
+	}
+}
+
+func TestReplaceLib(t *testing.T) {
+	old, err := ReplaceLib("field", func(tag *GKTag, data *D) string {
+		return "[" + tag.GetAttribute("name") + "]"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ReplaceLib("field", old)
+
+	rs, err := ParseString(`Hello <{gk:field name="info"/}>`, D{"info": "GoKeep"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs != "Hello [info]" {
+		t.Errorf("ReplaceLib result=%q", rs)
+	}
+}
+
+func TestExtFuncs(t *testing.T) {
+	ExtFuncs(map[string]TagFunc{
+		"RegQuote": func(v *string, args ...interface{}) string {
+			return "'" + *v + "'"
+		},
+	})
+	defer UnregisterFunc("RegQuote")
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("ExtFuncs with an existing name should panic")
+		}
+	}()
+	ExtFuncs(map[string]TagFunc{"RegQuote": FuncToUpper})
+}
+
+// 函数参数以[]interface{}整体传入，参数均为字符串
+func TestFuncArgs(t *testing.T) {
+	e := New()
+	var got []interface{}
+	e.RegisterFunc("Args", func(v *string, args ...interface{}) string {
+		if len(args) == 1 {
+			got, _ = args[0].([]interface{})
+		}
+		return *v
+	})
+	_, err := e.ParseString(`<{gk:field name="title" func="Args(@me, 30, 'a b', true)"/}>`, D{"title": "GoKeep"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []interface{}{"GoKeep", "30", "a b", "true"}
+	if len(got) != len(expect) {
+		t.Fatalf("args=%#v, expect %#v", got, expect)
+	}
+	for i := range expect {
+		if got[i] != expect[i] {
+			t.Errorf("arg %d=%#v, expect %#v", i, got[i], expect[i])
+		}
+	}
+}
+
+// 渲染过程中并发注册、删除标签
+func TestRegistryConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ReplaceLib("regswap", TagField)
+				UnregisterLib("regswap")
+				Libs()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ParseString(`<{gk:regswap name="info"/}>`, D{"info": "GoKeep"})
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,263 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板渲染
+package gktemplate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	attr "github.com/gokeeptech/gktemplate/attribute"
+)
+
+// RenderError 模板渲染错误，包含出错标签的位置
+type RenderError struct {
+	Name string // 模板名称
+	Pos  int    // 字节位置
+	Line int    // 行号，从1开始
+	Col  int    // 列号，按字符计算，从1开始
+	Tag  string // 标签，例：gk:call
+	Msg  string // 错误信息
+}
+
+func (re *RenderError) Error() string {
+	if re.Name == "" {
+		return fmt.Sprintf("[GKTemplate]line %d col %d: %s", re.Line, re.Col, re.Msg)
+	}
+	return fmt.Sprintf("[GKTemplate]%s:%d:%d: %s", re.Name, re.Line, re.Col, re.Msg)
+}
+
+// 渲染上下文，仅在渲染期间有效
+type renderContext struct {
+	engine *Engine
+	err    error // 渲染过程中的第一个错误
+
+	globals map[string]interface{} // 本次渲染读取过的全局变量
+	macros  map[string]*macro      // 已定义的宏
+	imports map[string]bool        // 已导入的模板
+	depth   int                    // 宏调用深度
+	parents map[*D]*D              // 块标签内部作用域的外层作用域
+
+	limits     Limits    // 资源限制
+	deadline   time.Time // 渲染截止时间
+	tags       int       // 已执行的标签次数
+	iterations int       // 已执行的循环次数
+}
+
+// 记录渲染错误，只保留第一个
+func (ctx *renderContext) fail(err error) {
+	if ctx.err == nil {
+		ctx.err = err
+	}
+}
+
+// 渲染模板
+// 数据先复制一份作为最外层作用域，set标签不会修改调用者传入的数据
+func (e *Engine) render(gktp *GKTemplate, data D) (string, error) {
+	ctx, scope := e.newContext(data)
+	s := ctx.render(gktp, scope)
+	if ctx.err != nil {
+		return "", ctx.err
+	}
+	return s, nil
+}
+
+// 创建渲染上下文并复制数据作为最外层作用域
+func (e *Engine) newContext(data D) (*renderContext, *D) {
+	scope := make(D, len(data))
+	for k, v := range data {
+		scope[k] = v
+	}
+	ctx := &renderContext{engine: e, limits: e.Limits()}
+	if ctx.limits.Timeout > 0 {
+		ctx.deadline = time.Now().Add(ctx.limits.Timeout)
+	}
+	return ctx, &scope
+}
+
+// 在data作用域中渲染模板，标签按顺序执行，set标签设置的变量对之后的标签可见
+func (ctx *renderContext) render(gktp *GKTemplate, data *D) string {
+	p := ctx.engine.program(gktp)
+	ctx.define(p.defs, data)
+
+	// 缓存的模板可能被并发渲染，这里使用标签副本保存渲染结果，副本在标签之间复用
+	tag := new(GKTag)
+	var sb strings.Builder
+	sb.Grow(p.size)
+	for i := range p.ops {
+		sb.WriteString(p.texts[i])
+		if !ctx.exec(&p.ops[i], tag, data, &sb) {
+			return ""
+		}
+	}
+	sb.WriteString(p.texts[len(p.ops)])
+	if !ctx.checkOutput(&GKTag{tpl: gktp, StartPos: p.tail}, sb.Len()) {
+		return ""
+	}
+	return sb.String()
+}
+
+// 使用标签副本tag执行标签并输出结果，出错或超过资源限制时返回false
+func (ctx *renderContext) exec(o *op, tag *GKTag, data *D, sb *strings.Builder) bool {
+	if ctx.err != nil {
+		return false
+	}
+	*tag = *o.tag
+	tag.ctx = ctx
+	tag.schema = o.schema
+	if !ctx.evaluate(tag) {
+		return false
+	}
+	if tag.isField {
+		// [field:name/]直接从当前作用域取值
+		tag.TagValue = toString(tag.Lookup(data, tag.TagName))
+	} else if o.lib != nil {
+		tag.TagValue = o.lib(tag, data)
+	} else {
+		return true
+	}
+	tag.IsReplace = true
+
+	// 处理自定义函数
+	if !tag.funcDone {
+		tag.TagValue = o.call(tag.TagValue)
+	}
+	if tag.TagValue != "#@Delete@#" {
+		sb.WriteString(tag.TagValue)
+	}
+	return ctx.err == nil && ctx.checkOutput(tag, sb.Len())
+}
+
+// 使用标签的func属性处理值，未设置func或函数不存在则原样返回
+// 参数中的@me替换为当前值，例：func="cn_substr(@me,30)"
+func (gktag *GKTag) callFunc(v string) string {
+	tplFunc := gktag.GetAttribute("func")
+	if tplFunc == "" {
+		return v
+	}
+	// 解析模板函数
+	funcName, args, err := attr.FuncParser(tplFunc)
+	if err != nil {
+		return v
+	}
+	tagfunc, ok := gktag.Engine().funcs.Get(funcName)
+	if !ok {
+		return v
+	}
+	for i, arg := range args {
+		if arg == "@me" {
+			args[i] = v
+		}
+	}
+	return tagfunc(&v, args)
+}
+
+// 渲染块标签的内部文本
+// 内部文本在新的作用域中渲染：先复制data，再加入scope中的变量，
+// 内部的set标签只影响该作用域，内部文本可以使用外层模板的标签和[field:name/]标记
+func (gktag *GKTag) RenderInner(data *D, scope D) string {
+	return gktag.context().renderInner(gktag, data, scope)
+}
+
+func (ctx *renderContext) renderInner(gktag *GKTag, data *D, scope D) string {
+	tpl, err := gktag.parseInner()
+	if err != nil {
+		ctx.fail(err)
+		return ""
+	}
+
+	// 内部作用域只保存本层变量，外层变量沿作用域链查找，不复制外层数据
+	child := make(D, len(scope))
+	for k, v := range scope {
+		child[k] = v
+	}
+	if data != nil {
+		if ctx.parents == nil {
+			ctx.parents = make(map[*D]*D)
+		}
+		ctx.parents[&child] = data
+	}
+	return ctx.render(tpl, &child)
+}
+
+// 从作用域链中取变量，内层作用域优先，ctx为nil时只查找data本身
+func (ctx *renderContext) scopeValue(data *D, key string) (interface{}, bool) {
+	for d := data; d != nil; {
+		if v, ok := (*d)[key]; ok {
+			return v, true
+		}
+		if ctx == nil {
+			break
+		}
+		d = ctx.parents[d]
+	}
+	return nil, false
+}
+
+// 作用域链最外层的渲染数据
+func (ctx *renderContext) root(data *D) *D {
+	if ctx == nil {
+		return data
+	}
+	for data != nil {
+		parent := ctx.parents[data]
+		if parent == nil {
+			break
+		}
+		data = parent
+	}
+	return data
+}
+
+// 合并作用域链中的变量，内层作用域优先
+func (ctx *renderContext) flatten(data *D) D {
+	var chain []*D
+	for d := data; d != nil; {
+		chain = append(chain, d)
+		if ctx == nil {
+			break
+		}
+		d = ctx.parents[d]
+	}
+	all := D{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for k, v := range *chain[i] {
+			all[k] = v
+		}
+	}
+	return all
+}
+
+// 获取渲染上下文，不在渲染过程中则使用默认引擎创建
+func (gktag *GKTag) context() *renderContext {
+	if gktag.ctx == nil {
+		return &renderContext{engine: defaultEngine}
+	}
+	return gktag.ctx
+}
+
+// 创建标签位置的渲染错误，位置按最外层模板计算
+func (gktag *GKTag) errorf(format string, args ...interface{}) *RenderError {
+	re := &RenderError{
+		Pos: gktag.StartPos,
+		Msg: fmt.Sprintf(format, args...),
+	}
+	if gktag.TagName != "" {
+		re.Tag = gktag.NameSpace + ":" + gktag.TagName
+	}
+	src := ""
+	if tpl := gktag.tpl; tpl != nil {
+		re.Name = tpl.Name
+		re.Pos += tpl.offset
+		src = tpl.root
+	}
+	re.Line, re.Col = position(src, re.Pos)
+	return re
+}
+
+// 记录标签渲染错误，只保留第一个
+func (gktag *GKTag) Fail(format string, args ...interface{}) {
+	gktag.context().fail(gktag.errorf(format, args...))
+}
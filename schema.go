@@ -0,0 +1,163 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 标签属性定义
+package gktemplate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// 属性类型
+type AttrType int
+
+const (
+	AttrString AttrType = iota // 字符串
+	AttrInt                    // 整数
+	AttrFloat                  // 浮点数
+	AttrBool                   // 布尔值
+)
+
+func (at AttrType) String() string {
+	switch at {
+	case AttrInt:
+		return "int"
+	case AttrFloat:
+		return "float"
+	case AttrBool:
+		return "bool"
+	}
+	return "string"
+}
+
+// AttrSchema 属性定义
+type AttrSchema struct {
+	Name     string   // 属性名称
+	Type     AttrType // 属性类型
+	Required bool     // 是否必填
+	Default  string   // 默认值，属性不存在时GetAttribute返回默认值
+	Values   []string // 允许的取值，为空则不限制
+}
+
+// 校验属性值
+func (as *AttrSchema) check(v string) error {
+	var err error
+	switch as.Type {
+	case AttrInt:
+		_, err = strconv.Atoi(v)
+	case AttrFloat:
+		_, err = strconv.ParseFloat(v, 64)
+	case AttrBool:
+		_, err = strconv.ParseBool(v)
+	}
+	if err != nil {
+		return fmt.Errorf("attribute '%s' must be %s, got '%s'", as.Name, as.Type, v)
+	}
+	if len(as.Values) == 0 {
+		return nil
+	}
+	for _, av := range as.Values {
+		if av == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("attribute '%s' must be one of %s, got '%s'", as.Name, strings.Join(as.Values, ","), v)
+}
+
+// 标签形式
+type TagKind int
+
+const (
+	TagAny         TagKind = iota // 不限制
+	TagSelfClosing                // 自闭合标签，例：<{gk:field/}>
+	TagBlock                      // 块标签，例：<{gk:range}>...<{/gk:range}>
+)
+
+// TagSchema 标签定义
+type TagSchema struct {
+	Kind     TagKind      // 标签形式
+	Attrs    []AttrSchema // 属性定义
+	AnyAttrs bool         // 是否允许未定义的属性，例如call标签的宏参数
+}
+
+// 获取属性定义
+func (ts *TagSchema) Attr(name string) *AttrSchema {
+	for i := range ts.Attrs {
+		if ts.Attrs[i].Name == name {
+			return &ts.Attrs[i]
+		}
+	}
+	return nil
+}
+
+// 所有标签都可以使用的属性
+var commonAttrs = map[string]bool{
+	"tagname": true,
+	"func":    true,
+}
+
+// 内置标签定义
+var (
+	schemaField = &TagSchema{
+		Kind: TagSelfClosing,
+		Attrs: []AttrSchema{
+			{Name: "name", Required: true},
+		},
+	}
+	schemaRange = &TagSchema{
+		Kind: TagBlock,
+		Attrs: []AttrSchema{
+			{Name: "name", Required: true},
+			{Name: "as"},
+		},
+	}
+)
+
+// 设置标签定义，schema为nil则删除定义
+func (e *Engine) SetSchema(name string, schema *TagSchema) {
+	e.Lock()
+	defer e.Unlock()
+	defer atomic.AddUint64(&e.schemaVersion, 1)
+	if schema == nil {
+		delete(e.schemas, name)
+		return
+	}
+	e.schemas[name] = schema
+}
+
+// 获取标签定义
+func (e *Engine) Schema(name string) *TagSchema {
+	e.RLock()
+	defer e.RUnlock()
+	return e.schemas[name]
+}
+
+// 注册带属性定义的标签，名称已存在则返回错误
+func (e *Engine) RegisterTag(name string, lib TagLib, schema *TagSchema) error {
+	if err := e.RegisterLib(name, lib); err != nil {
+		return err
+	}
+	e.SetSchema(name, schema)
+	return nil
+}
+
+// 获取模板中标签的定义
+func (e *Engine) lookupSchema(tpl *GKTemplate, tag *GKTag) *TagSchema {
+	if tag.NameSpace == "" || tag.NameSpace == tpl.NameSpace {
+		return e.Schema(tag.TagName)
+	}
+	return e.Schema(tag.NameSpace + ":" + tag.TagName)
+}
+
+// 在默认引擎注册带属性定义的标签
+func RegisterTag(name string, lib TagLib, schema *TagSchema) error {
+	return defaultEngine.RegisterTag(name, lib, schema)
+}
+
+// 设置默认引擎的标签定义
+func SetSchema(name string, schema *TagSchema) {
+	defaultEngine.SetSchema(name, schema)
+}
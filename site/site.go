@@ -0,0 +1,327 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 静态站点生成，类似DedeCMS的“生成HTML”
+package site
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+// 列表页分页数据的键名，gk:pagelist标签默认读取这些键
+const (
+	KeyPage      = "page"      // 当前页码，从1开始
+	KeyPageSize  = "pagesize"  // 每页条数
+	KeyTotal     = "total"     // 总条数
+	KeyTotalPage = "totalpage" // 总页数
+)
+
+// Errors
+var (
+	errNoTemplate = errors.New("site page template is empty")
+	errNoOutput   = errors.New("site page output is empty")
+	errNoItems    = errors.New("site list items key is empty")
+)
+
+// Site 站点描述
+type Site struct {
+	Root   string `json:"root" yaml:"root"`     // 模板和数据文件目录
+	Output string `json:"output" yaml:"output"` // 输出目录
+	Data   string `json:"data" yaml:"data"`     // 全站公共数据文件
+	Pages  []Page `json:"pages" yaml:"pages"`   // 单页
+	Lists  []List `json:"lists" yaml:"lists"`   // 列表页
+}
+
+// Page 单页
+type Page struct {
+	Template string                 `json:"template" yaml:"template"` // 模板文件
+	Data     string                 `json:"data" yaml:"data"`         // 数据文件
+	Vars     map[string]interface{} `json:"vars" yaml:"vars"`         // 页面数据，覆盖数据文件中的同名数据
+	Output   string                 `json:"output" yaml:"output"`     // 输出文件
+}
+
+// List 列表页，按Items指定的数据分页生成
+type List struct {
+	Template string                 `json:"template" yaml:"template"`
+	Data     string                 `json:"data" yaml:"data"`
+	Vars     map[string]interface{} `json:"vars" yaml:"vars"`
+	Items    string                 `json:"items" yaml:"items"`       // 列表数据的键名
+	PageSize int                    `json:"pagesize" yaml:"pagesize"` // 每页条数，默认10
+	Output   string                 `json:"output" yaml:"output"`     // 输出文件，{page}替换为页码，例：news/list_{page}.html
+	First    string                 `json:"first" yaml:"first"`       // 第一页输出文件，为空则使用Output，例：news/index.html
+}
+
+// 数据文件解码器
+type Decoder func(b []byte) (interface{}, error)
+
+// Result 页面生成结果
+type Result struct {
+	Template string        // 模板文件
+	Output   string        // 输出文件
+	Page     int           // 列表页页码，单页为0
+	Changed  bool          // 文件内容是否有变化
+	Duration time.Duration // 渲染耗时
+}
+
+// Generator 站点生成器
+type Generator struct {
+	Engine   *gkt.Engine        // 模板引擎，为空则使用默认引擎
+	Decoders map[string]Decoder // 数据文件解码器，键为扩展名，默认支持json
+	Force    bool               // 内容没有变化也重写文件
+}
+
+// 从JSON文件载入站点描述，Root、Output为相对路径时基于描述文件所在目录
+func Load(filename string) (*Site, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var s Site
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	s.Resolve(filepath.Dir(filename))
+	return &s, nil
+}
+
+// 将相对路径的Root、Output转换为基于dir的路径
+func (s *Site) Resolve(dir string) {
+	if !filepath.IsAbs(s.Root) {
+		s.Root = filepath.Join(dir, s.Root)
+	}
+	if !filepath.IsAbs(s.Output) {
+		s.Output = filepath.Join(dir, s.Output)
+	}
+}
+
+func (g *Generator) engine() *gkt.Engine {
+	if g.Engine == nil {
+		return gkt.Default()
+	}
+	return g.Engine
+}
+
+// 读取数据文件
+func (g *Generator) loadData(root, filename string) (gkt.D, error) {
+	d := gkt.D{}
+	if filename == "" {
+		return d, nil
+	}
+	b, err := ioutil.ReadFile(filepath.Join(root, filename))
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	dec, ok := g.Decoders[ext]
+	if !ok {
+		if ext != "json" {
+			return nil, fmt.Errorf("%s: unsupported data format", filename)
+		}
+		dec = decodeJSON
+	}
+	v, err := dec(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	if v == nil {
+		return d, nil
+	}
+	d, ok = Normalize(v).(gkt.D)
+	if !ok {
+		return nil, fmt.Errorf("%s: data must be an object", filename)
+	}
+	return d, nil
+}
+
+func decodeJSON(b []byte) (interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+// 合并页面数据，后面的覆盖前面的
+func merge(ds ...gkt.D) gkt.D {
+	rs := gkt.D{}
+	for _, d := range ds {
+		for k, v := range d {
+			rs[k] = v
+		}
+	}
+	return rs
+}
+
+// 生成站点，返回每个页面的生成结果
+func (g *Generator) Build(s *Site) ([]Result, error) {
+	global, err := g.loadData(s.Root, s.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, p := range s.Pages {
+		if p.Template == "" {
+			return results, errNoTemplate
+		}
+		if p.Output == "" {
+			return results, errNoOutput
+		}
+		d, err := g.loadData(s.Root, p.Data)
+		if err != nil {
+			return results, err
+		}
+		rs, err := g.write(s, p.Template, p.Output, 0, merge(global, d, Normalize(p.Vars).(gkt.D)))
+		if err != nil {
+			return results, err
+		}
+		results = append(results, rs)
+	}
+
+	for _, l := range s.Lists {
+		rss, err := g.buildList(s, &l, global)
+		results = append(results, rss...)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// 生成分页列表
+func (g *Generator) buildList(s *Site, l *List, global gkt.D) ([]Result, error) {
+	if l.Template == "" {
+		return nil, errNoTemplate
+	}
+	if l.Output == "" {
+		return nil, errNoOutput
+	}
+	if l.Items == "" {
+		return nil, errNoItems
+	}
+	d, err := g.loadData(s.Root, l.Data)
+	if err != nil {
+		return nil, err
+	}
+	base := merge(global, d, Normalize(l.Vars).(gkt.D))
+
+	var items []gkt.D
+	switch v := base[l.Items].(type) {
+	case []gkt.D:
+		items = v
+	case nil:
+	default:
+		return nil, fmt.Errorf("%s: list items '%s' must be an array of objects", l.Template, l.Items)
+	}
+
+	size := l.PageSize
+	if size <= 0 {
+		size = 10
+	}
+	total := len(items)
+	totalPage := int(math.Ceil(float64(total) / float64(size)))
+	if totalPage == 0 {
+		totalPage = 1
+	}
+
+	var results []Result
+	for page := 1; page <= totalPage; page++ {
+		start := (page - 1) * size
+		end := start + size
+		if end > total {
+			end = total
+		}
+		pd := merge(base, gkt.D{
+			l.Items:      items[start:end],
+			KeyPage:      page,
+			KeyPageSize:  size,
+			KeyTotal:     total,
+			KeyTotalPage: totalPage,
+		})
+		out := strings.Replace(l.Output, "{page}", strconv.Itoa(page), -1)
+		if page == 1 && l.First != "" {
+			out = l.First
+		}
+		rs, err := g.write(s, l.Template, out, page, pd)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, rs)
+	}
+	return results, nil
+}
+
+// 渲染页面并写入文件，内容没有变化则不重写
+func (g *Generator) write(s *Site, tpl, out string, page int, data gkt.D) (Result, error) {
+	rs := Result{Template: tpl, Output: out, Page: page}
+
+	t := time.Now()
+	html, err := g.engine().ParseFile(filepath.Join(s.Root, tpl), data)
+	rs.Duration = time.Since(t)
+	if err != nil {
+		return rs, err
+	}
+
+	filename := filepath.Join(s.Output, out)
+	b := []byte(html)
+	if !g.Force {
+		if old, err := ioutil.ReadFile(filename); err == nil && bytes.Equal(old, b) {
+			return rs, nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return rs, err
+	}
+	if err := ioutil.WriteFile(filename, b, 0644); err != nil {
+		return rs, err
+	}
+	rs.Changed = true
+	return rs, nil
+}
+
+// 转换解码后的数据为模板使用的数据结构：对象转为D，对象数组转为[]D，整数值的浮点数转为int
+func Normalize(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		d := make(gkt.D, len(vv))
+		for k, item := range vv {
+			d[k] = Normalize(item)
+		}
+		return d
+	case gkt.D:
+		return Normalize(map[string]interface{}(vv))
+	case []interface{}:
+		items := make([]interface{}, len(vv))
+		allD := true // 空数组也转为[]D，作为没有数据的列表
+		for i, item := range vv {
+			items[i] = Normalize(item)
+			if _, ok := items[i].(gkt.D); !ok {
+				allD = false
+			}
+		}
+		if !allD {
+			return items
+		}
+		ds := make([]gkt.D, len(items))
+		for i, item := range items {
+			ds[i] = item.(gkt.D)
+		}
+		return ds
+	case float64:
+		if vv == math.Trunc(vv) && math.Abs(vv) < 1e15 {
+			return int(vv)
+		}
+		return vv
+	}
+	return v
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 静态站点生成单元测试
+package site
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gkt "github.com/gokeeptech/gktemplate"
+)
+
+func TestBuild(t *testing.T) {
+	s, err := Load("testdata/site.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.TempDir("", "gksite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+	s.Output = out
+
+	g := &Generator{Engine: gkt.New()}
+	results, err := g.Build(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Build got %d pages, expect 3", len(results))
+	}
+	for _, rs := range results {
+		if !rs.Changed {
+			t.Errorf("%s should be written", rs.Output)
+		}
+	}
+
+	expect := map[string]string{
+		"index.html":       "<title>首页 - GoKeep</title>\n",
+		"news/index.html":  "GoKeep 1/2\n<li>1:a</li><li>2:b</li>\n",
+		"news/list_2.html": "GoKeep 2/2\n<li>3:c</li>\n",
+	}
+	for f, content := range expect {
+		b, err := ioutil.ReadFile(filepath.Join(out, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("%s=%q", f, string(b))
+		}
+	}
+
+	// 再次生成，内容没有变化则不重写
+	results, err = g.Build(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rs := range results {
+		if rs.Changed {
+			t.Errorf("%s should not be rewritten", rs.Output)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	v := Normalize(map[string]interface{}{
+		"n":     float64(3),
+		"f":     1.5,
+		"items": []interface{}{map[string]interface{}{"id": float64(1)}},
+		"tags":  []interface{}{"a", nil},
+		"empty": []interface{}{},
+	}).(gkt.D)
+	if v["n"] != 3 || v["f"] != 1.5 {
+		t.Errorf("Normalize number=%v", v)
+	}
+	if items, ok := v["items"].([]gkt.D); !ok || items[0]["id"] != 1 {
+		t.Errorf("Normalize items=%#v", v["items"])
+	}
+	if tags, ok := v["tags"].([]interface{}); !ok || tags[1] != nil {
+		t.Errorf("Normalize tags=%#v", v["tags"])
+	}
+	if empty, ok := v["empty"].([]gkt.D); !ok || len(empty) != 0 {
+		t.Errorf("Normalize empty=%#v", v["empty"])
+	}
+}
+
+// 列表数据为空数组时生成一个空的列表页
+func TestBuildEmptyList(t *testing.T) {
+	out, err := ioutil.TempDir("", "gksite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+	s := &Site{
+		Root:   "testdata",
+		Output: out,
+		Lists: []List{{
+			Template: "tpl/list.htm",
+			Vars:     map[string]interface{}{"sitename": "GoKeep", "news": []interface{}{}},
+			Items:    "news",
+			Output:   "news/list_{page}.html",
+			First:    "news/index.html",
+		}},
+	}
+
+	g := &Generator{Engine: gkt.New()}
+	results, err := g.Build(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Build got %d pages, expect 1", len(results))
+	}
+	b, err := ioutil.ReadFile(filepath.Join(out, "news/index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "GoKeep 1/1\n\n" {
+		t.Errorf("news/index.html=%q", string(b))
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板校验
+package gktemplate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	attr "github.com/gokeeptech/gktemplate/attribute"
+)
+
+// 诊断级别
+type Severity int
+
+const (
+	SeverityError   Severity = iota // 错误
+	SeverityWarning                 // 警告
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic 模板校验结果，包含出错位置
+type Diagnostic struct {
+	Name     string   // 模板名称
+	Pos      int      // 字节位置
+	Line     int      // 行号，从1开始
+	Col      int      // 列号，按字符计算，从1开始
+	Severity Severity // 级别
+	Tag      string   // 标签，例：gk:range
+	Msg      string   // 信息
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.Name, d.Line, d.Col, d.Severity, d.Msg)
+}
+
+// 是否包含错误级别的诊断
+func HasError(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// 模板校验器
+type validator struct {
+	engine *Engine
+	name   string
+	src    string
+	cfg    *parseConfig
+	diags  []Diagnostic
+
+	visit func(pos int, tpl *GKTemplate, tag *GKTag) // 遍历标签时调用
+}
+
+func (v *validator) add(pos int, sev Severity, tag, format string, args ...interface{}) {
+	line, col := position(v.src, pos)
+	v.diags = append(v.diags, Diagnostic{
+		Name:     v.name,
+		Pos:      pos,
+		Line:     line,
+		Col:      col,
+		Severity: sev,
+		Tag:      tag,
+		Msg:      fmt.Sprintf(format, args...),
+	})
+}
+
+// 校验src[start:end]中的标签，块标签的内部文本递归校验
+func (v *validator) check(start, end int) {
+	tpl, err := parseSource(v.src[start:end], v.cfg)
+	if err != nil {
+		if pe, ok := err.(*ParseError); ok {
+			v.add(start+pe.Pos, SeverityError, "", "%s", pe.Msg)
+		} else {
+			v.add(start, SeverityError, "", "%s", err)
+		}
+		return
+	}
+
+	for i := 0; i < tpl.Count; i++ {
+		tag := tpl.CTags[i]
+		if tag.literal {
+			continue
+		}
+		pos := start + tag.StartPos
+		full := tag.NameSpace + ":" + tag.TagName
+		if v.visit != nil {
+			v.visit(pos, tpl, tag)
+		}
+
+		if _, ok := v.engine.lookupTag(tpl, tag); !ok {
+			v.add(pos, SeverityError, full, "unknown tag '%s'", full)
+		} else if schema := v.engine.lookupSchema(tpl, tag); schema != nil {
+			v.checkSchema(pos, full, tag, schema)
+		}
+
+		if tag.InnerPos >= 0 {
+			v.check(start+tag.InnerPos, start+tag.InnerPos+len(tag.innerText))
+		}
+	}
+}
+
+// 按标签定义校验标签形式和属性
+func (v *validator) checkSchema(pos int, full string, tag *GKTag, schema *TagSchema) {
+	if schema.Kind == TagBlock && tag.InnerPos < 0 {
+		v.add(pos, SeverityError, full, "tag '%s' must be a block tag", full)
+	}
+	if schema.Kind == TagSelfClosing && tag.InnerPos >= 0 {
+		v.add(pos, SeverityError, full, "tag '%s' must be self-closing", full)
+	}
+
+	for i := range schema.Attrs {
+		as := &schema.Attrs[i]
+		av, ok := tag.CAttribute.Items[as.Name]
+		if !ok || av == "" {
+			if as.Required {
+				v.add(pos, SeverityError, full, "tag '%s' missing required attribute '%s'", full, as.Name)
+			}
+			continue
+		}
+		if err := as.check(av); err != nil {
+			v.add(pos, SeverityError, full, "tag '%s' %s", full, err)
+		}
+	}
+
+	if schema.AnyAttrs {
+		return
+	}
+	names := make([]string, 0, len(tag.CAttribute.Items))
+	for name := range tag.CAttribute.Items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !commonAttrs[name] && schema.Attr(name) == nil {
+			v.add(pos, SeverityWarning, full, "tag '%s' unknown attribute '%s'", full, name)
+		}
+	}
+}
+
+func (e *Engine) newValidator(name, tplstr string) *validator {
+	ns, start, end := e.NameSpace()
+	return &validator{
+		engine: e,
+		name:   name,
+		src:    tplstr,
+		cfg: &parseConfig{
+			Name:       name,
+			NameSpaces: e.nameSpaces(ns, start, end),
+			TagStart:   start,
+			TagEnd:     end,
+			Dede:       e.DedeCompat(),
+		},
+	}
+}
+
+// 校验模板字符串，返回按位置排序的诊断结果
+func (e *Engine) Validate(name, tplstr string) []Diagnostic {
+	v := e.newValidator(name, tplstr)
+	v.check(0, len(v.src))
+	sort.SliceStable(v.diags, func(i, j int) bool {
+		return v.diags[i].Pos < v.diags[j].Pos
+	})
+	return v.diags
+}
+
+// TagUsage 模板中使用的标签
+type TagUsage struct {
+	Tag   string   // 标签，例：gk:range
+	Line  int      // 行号
+	Col   int      // 列号
+	Block bool     // 是否块标签
+	Attrs []string // 使用的属性名称
+	Func  string   // func属性中调用的函数名称
+	Known bool     // 标签是否已注册
+}
+
+// 列出模板中使用的标签（包括块标签内部的标签），同时返回校验结果
+func (e *Engine) Inspect(name, tplstr string) ([]TagUsage, []Diagnostic) {
+	var usages []TagUsage
+	v := e.newValidator(name, tplstr)
+	v.visit = func(pos int, tpl *GKTemplate, tag *GKTag) {
+		line, col := position(v.src, pos)
+		u := TagUsage{
+			Tag:   tag.NameSpace + ":" + tag.TagName,
+			Line:  line,
+			Col:   col,
+			Block: tag.InnerPos >= 0,
+		}
+		_, u.Known = e.lookupTag(tpl, tag)
+		for an := range tag.CAttribute.Items {
+			if an != "tagname" {
+				u.Attrs = append(u.Attrs, an)
+			}
+		}
+		sort.Strings(u.Attrs)
+		if fs := tag.CAttribute.GetAtt("func"); fs != "" {
+			if fn, _, err := attr.FuncParser(fs); err == nil {
+				u.Func = fn
+			}
+		}
+		usages = append(usages, u)
+	}
+	v.check(0, len(v.src))
+	sort.SliceStable(usages, func(i, j int) bool {
+		if usages[i].Line != usages[j].Line {
+			return usages[i].Line < usages[j].Line
+		}
+		return usages[i].Col < usages[j].Col
+	})
+	return usages, v.diags
+}
+
+// 校验模板文件
+func (e *Engine) ValidateFile(filename string) ([]Diagnostic, error) {
+	d, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return e.Validate(filename, string(d)), nil
+}
+
+// 校验LoadDir加载的所有模板文件
+func (e *Engine) Lint() ([]Diagnostic, error) {
+	var diags []Diagnostic
+	for _, f := range e.Loaded() {
+		ds, err := e.ValidateFile(f)
+		if err != nil {
+			return diags, err
+		}
+		diags = append(diags, ds...)
+	}
+	return diags, nil
+}
+
+// 使用默认引擎校验模板字符串
+func Validate(name, tplstr string) []Diagnostic {
+	return defaultEngine.Validate(name, tplstr)
+}
+
+// 使用默认引擎校验LoadDir加载的所有模板文件
+func Lint() ([]Diagnostic, error) {
+	return defaultEngine.Lint()
+}
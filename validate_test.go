@@ -0,0 +1,112 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 模板校验单元测试
+package gktemplate
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	e := New()
+	err := e.RegisterTag("list", TagField, &TagSchema{
+		Kind: TagSelfClosing,
+		Attrs: []AttrSchema{
+			{Name: "row", Type: AttrInt, Default: "10"},
+			{Name: "order", Values: []string{"asc", "desc"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := "<{gk:range nmae='items'}>\n" +
+		"  <{gk:list row='ten' order='up'/}>\n" +
+		"<{/gk:range}>\n" +
+		"<{gk:nope/}>"
+	diags := e.Validate("test.htm", tpl)
+	for _, d := range diags {
+		t.Log(d)
+	}
+
+	expect := []struct {
+		line, col int
+		sev       Severity
+	}{
+		{1, 1, SeverityError},   // 缺少name
+		{1, 1, SeverityWarning}, // 未知属性nmae
+		{2, 3, SeverityError},   // row类型错误
+		{2, 3, SeverityError},   // order取值错误
+		{4, 1, SeverityError},   // 未知标签
+	}
+	if len(diags) != len(expect) {
+		t.Fatalf("Validate got %d diagnostics, expect %d", len(diags), len(expect))
+	}
+	for i, ex := range expect {
+		if diags[i].Line != ex.line || diags[i].Col != ex.col || diags[i].Severity != ex.sev {
+			t.Errorf("diagnostic %d=%s", i, diags[i])
+		}
+	}
+	if !HasError(diags) {
+		t.Errorf("HasError should be true")
+	}
+}
+
+func TestValidateParseError(t *testing.T) {
+	diags := New().Validate("test.htm", "<{gk:range name='a'}>\n<{gk:range name='b'}><{/gk:range}>")
+	if len(diags) != 1 || diags[0].Line != 1 {
+		t.Errorf("Validate parse error=%v", diags)
+	}
+}
+
+func TestSchemaDefault(t *testing.T) {
+	e := New()
+	e.RegisterTag("row", func(tag *GKTag, data *D) string {
+		return tag.GetAttribute("row")
+	}, &TagSchema{Attrs: []AttrSchema{{Name: "row", Default: "10"}}})
+
+	rs, err := e.ParseString(`<{gk:row/}>,<{gk:row row="5"/}>`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs != "10,5" {
+		t.Errorf("schema default result=%q", rs)
+	}
+}
+
+func TestLint(t *testing.T) {
+	e := New()
+	e.LoadDir("testdata/deep/*.htm")
+	diags, err := e.Lint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// deep/tpl1.htm中的gk:if为未注册标签
+	if !HasError(diags) {
+		t.Errorf("Lint should report unknown tag gk:if")
+	}
+}
+
+// 出错的文件不影响其余文件的加载和校验
+func TestLintAll(t *testing.T) {
+	e := New()
+	if err := e.LoadDir("testdata/lint/*.htm"); err == nil {
+		t.Errorf("LoadDir should return the parse error of a.htm")
+	}
+	if files := e.Loaded(); len(files) != 2 {
+		t.Errorf("Loaded=%v", files)
+	}
+	diags, err := e.Lint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, d := range diags {
+		names[filepath.Base(d.Name)] = true
+	}
+	if !names["a.htm"] || !names["b.htm"] {
+		t.Errorf("Lint should report both files: %v", diags)
+	}
+}
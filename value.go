@@ -0,0 +1,180 @@
+// Copyright 2020 The GoKeep Authors. All rights reserved.
+// license that can be found in the LICENSE file.
+
+// 数据取值
+package gktemplate
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// 从作用域中取值，名称不存在时按`.`逐级查找，例：article.title
+// 支持D、map[string]interface{}、map[string]string以及结构体字段（不区分大小写）
+func lookup(data D, name string) interface{} {
+	return lookupScope(nil, &data, name)
+}
+
+// 从作用域链中取值，内层作用域的变量优先
+func lookupScope(ctx *renderContext, data *D, name string) interface{} {
+	if v, ok := ctx.scopeValue(data, name); ok {
+		return v
+	}
+	i := strings.Index(name, ".")
+	if i <= 0 {
+		return nil
+	}
+	v, ok := ctx.scopeValue(data, name[:i])
+	if !ok {
+		return nil
+	}
+	for _, key := range strings.Split(name[i+1:], ".") {
+		v, ok = member(v, key)
+		if !ok {
+			return nil
+		}
+	}
+	return v
+}
+
+// 获取map的键或结构体的字段
+func member(v interface{}, key string) (interface{}, bool) {
+	switch m := v.(type) {
+	case nil:
+		return nil, false
+	case D:
+		mv, ok := m[key]
+		return mv, ok
+	case map[string]interface{}:
+		mv, ok := m[key]
+		return mv, ok
+	case map[string]string:
+		mv, ok := m[key]
+		return mv, ok
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		mv := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		if !mv.IsValid() {
+			return nil, false
+		}
+		return mv.Interface(), true
+	case reflect.Struct:
+		f := rv.FieldByNameFunc(func(name string) bool {
+			return strings.EqualFold(name, key)
+		})
+		if !f.IsValid() || !f.CanInterface() {
+			return nil, false
+		}
+		return f.Interface(), true
+	}
+	return nil, false
+}
+
+// 将map或结构体展开为作用域变量，其他类型返回false
+func fieldsOf(v interface{}) (D, bool) {
+	switch m := v.(type) {
+	case nil:
+		return nil, false
+	case D:
+		return m, true
+	case map[string]interface{}:
+		return D(m), true
+	case map[string]string:
+		d := make(D, len(m))
+		for k, mv := range m {
+			d[k] = mv
+		}
+		return d, true
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		d := make(D, rv.Len())
+		for _, k := range rv.MapKeys() {
+			d[k.String()] = rv.MapIndex(k).Interface()
+		}
+		return d, true
+	case reflect.Struct:
+		t := rv.Type()
+		d := make(D, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			d[t.Field(i).Name] = rv.Field(i).Interface()
+		}
+		return d, true
+	}
+	return nil, false
+}
+
+// 将列表数据转换为数据项，支持[]D、[]map[string]interface{}以及任意切片
+func itemsOf(v interface{}) []interface{} {
+	switch l := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return l
+	case []D:
+		items := make([]interface{}, len(l))
+		for i := range l {
+			items[i] = l[i]
+		}
+		return items
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
+}
+
+// 属性值中的变量，例：{article.title}
+var reVariable = regexp.MustCompile(`\{([A-Za-z0-9_][A-Za-z0-9_.]*)\}`)
+
+// 替换字符串中的{name}为作用域中的值，取值规则与field标签相同
+// 例：url="/news/{article.id}.html"
+func (gktag *GKTag) Interpolate(data *D, s string) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	return reVariable.ReplaceAllStringFunc(s, func(m string) string {
+		return toString(gktag.Lookup(data, m[1:len(m)-1]))
+	})
+}
+
+// 与Interpolate相同，但整个字符串为{name}时返回原始值，可以传递列表、map等数据
+func (gktag *GKTag) interpolateValue(data *D, s string) interface{} {
+	if m := reVariable.FindStringSubmatch(s); m != nil && m[0] == s {
+		return gktag.Lookup(data, m[1])
+	}
+	return gktag.Interpolate(data, s)
+}